@@ -0,0 +1,159 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// AccessLogCommon is the Common Log Format of Apache and Nginx
+	AccessLogCommon = "common"
+	// AccessLogCombined is the Common Log Format followed by the referer and
+	// the user agent
+	AccessLogCombined = "combined"
+)
+
+const accessLogDate = "02/Jan/2006:15:04:05 -0700"
+
+// maxAccessLogLine is the length in bytes above which a line is skipped, the
+// long user agents and referers fit well below it
+const maxAccessLogLine = 1 << 20
+
+var (
+	commonLogRe = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "\S+ (\S+)(?: ([^"]*))?" (\d{3}) (\d+|-)`)
+	combinedLogRe = regexp.MustCompile(commonLogRe.String() + ` "([^"]*)" "([^"]*)"`)
+)
+
+// AccessLogError is returned by ParseAccessLog along with the visits of the
+// valid lines when some lines couldn't be parsed
+type AccessLogError struct {
+	Skipped int
+	// FirstLine is the number (from 1) of the first skipped line
+	FirstLine int
+}
+
+func (e *AccessLogError) Error() string {
+	return fmt.Sprintf("%d malformed access log lines skipped, the first one is line %d", e.Skipped, e.FirstLine)
+}
+
+// ParseAccessLog reads an access log in the AccessLogCommon or
+// AccessLogCombined format into visits to give to Import, the authenticated
+// user becomes the UserID and a "-" response size 0. Malformed lines, including
+// the ones above maxAccessLogLine, are skipped and counted in an
+// *AccessLogError
+func ParseAccessLog(r io.Reader, format string) ([]VisitInput, error) {
+	var re *regexp.Regexp
+
+	switch format {
+	case AccessLogCommon:
+		re = commonLogRe
+	case AccessLogCombined:
+		re = combinedLogRe
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+
+	visits := []VisitInput{}
+	malformed := &AccessLogError{}
+	line := 0
+
+	reader := bufio.NewReaderSize(r, maxAccessLogLine)
+
+	for {
+		text, err := reader.ReadSlice('\n')
+		tooLong := err == bufio.ErrBufferFull
+
+		// the rest of an over-long line is dropped with it
+		for err == bufio.ErrBufferFull {
+			_, err = reader.ReadSlice('\n')
+		}
+
+		if err != nil && err != io.EOF {
+			return visits, err
+		}
+
+		if len(text) > 0 {
+			line++
+		}
+
+		if text = bytes.TrimRight(text, "\r\n"); len(text) > 0 {
+			visit, ok := VisitInput{}, false
+
+			if !tooLong {
+				visit, ok = parseAccessLogLine(re, string(text))
+			}
+
+			if ok {
+				visits = append(visits, visit)
+			} else {
+				if malformed.Skipped == 0 {
+					malformed.FirstLine = line
+				}
+
+				malformed.Skipped++
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if malformed.Skipped > 0 {
+		return visits, malformed
+	}
+
+	return visits, nil
+}
+
+func parseAccessLogLine(re *regexp.Regexp, line string) (VisitInput, bool) {
+	match := re.FindStringSubmatch(line)
+	if match == nil {
+		return VisitInput{}, false
+	}
+
+	date, err := time.Parse(accessLogDate, match[3])
+	if err != nil {
+		return VisitInput{}, false
+	}
+
+	target, err := url.ParseRequestURI(match[4])
+	if err != nil {
+		return VisitInput{}, false
+	}
+
+	code, _ := strconv.Atoi(match[6])
+	size, _ := strconv.ParseInt(match[7], 10, 64)
+
+	visit := VisitInput{
+		Date: date,
+		IP: match[1],
+		Path: target.Path,
+		Query: target.RawQuery,
+		CodeIssued: code,
+		ResponseSize: size,
+		Protocol: match[5],
+	}
+
+	if match[2] != "-" {
+		visit.UserID = match[2]
+	}
+
+	if len(match) > 9 {
+		if match[8] != "-" {
+			visit.Referer = match[8]
+		}
+
+		if match[9] != "-" {
+			visit.UserAgent = match[9]
+		}
+	}
+
+	return visit, true
+}
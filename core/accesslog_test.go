@@ -0,0 +1,45 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseAccessLogResponseSize(t *testing.T) {
+	log := strings.Join([]string{
+		`10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"`,
+		`10.0.0.1 - - [10/Oct/2024:13:55:37 +0000] "GET /empty HTTP/1.1" 304 - "-" "curl/8.0"`,
+	}, "\r\n")
+
+	visits, err := ParseAccessLog(strings.NewReader(log), AccessLogCombined)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(visits) != 2 || visits[0].ResponseSize != 512 || visits[1].ResponseSize != 0 {
+		t.Fatalf("parsed the response sizes %v, want 512 and 0", visits)
+	}
+
+	if visits[1].UserAgent != "curl/8.0" {
+		t.Errorf("UserAgent = %q, want curl/8.0", visits[1].UserAgent)
+	}
+}
+
+func TestParseAccessLogSkipsLongLines(t *testing.T) {
+	valid := `10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"`
+	long := `10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "` + strings.Repeat("a", maxAccessLogLine) + `"`
+	// a user agent above the 64KB of a default bufio.Scanner is kept
+	agent := `10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET / HTTP/1.1" 200 512 "-" "` + strings.Repeat("a", 100000) + `"`
+
+	visits, err := ParseAccessLog(strings.NewReader(strings.Join([]string{valid, long, agent, valid}, "\n")), AccessLogCombined)
+
+	var malformed *AccessLogError
+	if !errors.As(err, &malformed) || malformed.Skipped != 1 || malformed.FirstLine != 2 {
+		t.Fatalf("err = %v, want line 2 skipped", err)
+	}
+
+	if len(visits) != 3 || len(visits[1].UserAgent) != 100000 {
+		t.Errorf("parsed %d visits, want the 3 other lines", len(visits))
+	}
+}
@@ -0,0 +1,59 @@
+package core
+
+// Aggregates holds the headline counters without the visits detail, it can be
+// persisted to survive restarts at a fraction of the cost of the full state
+type Aggregates struct {
+	TotalVisits int `json:"total_visits"`
+	TotalVisitors int `json:"total_visitors"`
+	PageVisits map[string]int `json:"page_visits"`
+	Languages map[string]int `json:"languages"`
+}
+
+func (s *Statistics) ExportAggregates() Aggregates {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	aggregates := Aggregates{
+		TotalVisits: s.VisitsCount(),
+		TotalVisitors: s.VisitorsCount(),
+		PageVisits: make(map[string]int),
+		Languages: make(map[string]int),
+	}
+
+	for path, page := range s.Pages {
+		aggregates.PageVisits[path] = page.VisitsCount()
+	}
+
+	for language, count := range s.VisitorsLanguage {
+		aggregates.Languages[language] = count
+	}
+
+	return aggregates
+}
+
+// ImportAggregates adds the aggregates to the current counters, the imported
+// page visits are counted by Page.VisitsCount through Page.PriorVisits
+func (s *Statistics) ImportAggregates(aggregates Aggregates) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.visitsCount.Add(int64(aggregates.TotalVisits))
+	s.visitorsCount.Add(int64(aggregates.TotalVisitors))
+	s.importedVisits += aggregates.TotalVisits
+	s.importedVisitors += aggregates.TotalVisitors
+
+	for path, count := range aggregates.PageVisits {
+		if _, ok := s.Pages[path]; !ok {
+			s.Pages[path] = &Page{
+				Path: path,
+			}
+			s.trackPage(s.Pages[path])
+		}
+
+		s.Pages[path].PriorVisits += count
+	}
+
+	for language, count := range aggregates.Languages {
+		s.VisitorsLanguage[language] += count
+	}
+}
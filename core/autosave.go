@@ -0,0 +1,85 @@
+package core
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// startAutosave loads the autosave file if it exists then writes the
+// statistics to it every autosaveInterval until Close
+func (s *Statistics) startAutosave() {
+	if err := s.loadFile(s.autosavePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// don't overwrite a file that couldn't be read
+		s.errorLogger().Error("statistics: autosave disabled, can't load the file", "path", s.autosavePath, "error", err)
+		return
+	}
+
+	s.background(func(done <-chan struct{}) {
+		// without a positive interval the statistics are only saved by Close
+		var tick <-chan time.Time
+
+		if s.autosaveInterval > 0 {
+			ticker := time.NewTicker(s.autosaveInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-tick:
+				if err := s.saveFile(s.autosavePath); err != nil {
+					s.errorLogger().Error("statistics: autosave failed", "path", s.autosavePath, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+
+	s.onClose(func() error {
+		return s.saveFile(s.autosavePath)
+	})
+}
+
+func (s *Statistics) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return s.LoadBinary(file)
+}
+
+// saveFile writes to a temporary file renamed over path so a crash while
+// saving doesn't corrupt the previous save
+func (s *Statistics) saveFile(path string) error {
+	tmp := path + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := s.SaveBinary(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (s *Statistics) errorLogger() *slog.Logger {
+	if s.logger != nil {
+		return s.logger
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAutosaveKeepsWarmup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.bin")
+
+	previous := New(WithAutosave(path, time.Hour))
+	recordPage(previous, "10.0.0.1", "/")
+
+	if err := previous.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a restart during the warmup period of the new process
+	s := New(WithAutosave(path, time.Hour), WithWarmupPeriod(time.Minute))
+	defer s.Close()
+
+	if got := s.VisitsCount(); got != 1 {
+		t.Fatalf("loaded %d visits, want 1", got)
+	}
+
+	if visit := recordPage(s, "10.0.0.2", "/"); !visit.Warmup {
+		t.Errorf("a visit right after New isn't flagged as a warmup visit")
+	}
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"slices"
+)
+
+// ConversionRate returns the fraction of the visitors of fromPath that visited
+// goalPath afterwards, or 0 if nobody visited fromPath
+func (s *Statistics) ConversionRate(fromPath, goalPath string) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitors := 0
+	converted := 0
+
+	for _, v := range s.Visitors {
+		from := firstVisitTo(v.History, fromPath)
+		if from == nil {
+			continue
+		}
+
+		visitors++
+
+		if reachedAfter(v.History, from, goalPath) {
+			converted++
+		}
+	}
+
+	if visitors == 0 {
+		return 0
+	}
+
+	return float64(converted) / float64(visitors)
+}
+
+// ConversionByReferer returns, per referer host of the first visit of the
+// visitors (DirectTraffic when it had no referer), the fraction of them that
+// visited goalPath
+func (s *Statistics) ConversionByReferer(goalPath string) map[string]float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitors := make(map[string]int)
+	converted := make(map[string]int)
+
+	for _, v := range s.Visitors {
+		if len(v.History) == 0 {
+			continue
+		}
+
+		source := refererHost(v.History[0].Referer)
+		if source == "" {
+			source = DirectTraffic
+		}
+
+		visitors[source]++
+
+		if firstVisitTo(v.History, goalPath) != nil {
+			converted[source]++
+		}
+	}
+
+	rates := make(map[string]float64, len(visitors))
+
+	for source, count := range visitors {
+		rates[source] = float64(converted[source]) / float64(count)
+	}
+
+	return rates
+}
+
+func firstVisitTo(history []*Visit, path string) *Visit {
+	for _, v := range history {
+		if v.Page.Path == path {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// reachedAfter reports whether a visit to path happened after the given visit
+func reachedAfter(history []*Visit, after *Visit, path string) bool {
+	for _, v := range history {
+		if v.Page.Path == path && v.Date.After(after.Date) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ConversionCount counts the visits flagged by MarkConversion
+func (s *Statistics) ConversionCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+
+	for _, v := range s.Visits {
+		if v.Converted {
+			count++
+		}
+	}
+
+	return count
+}
+
+// ConvertedVisitorsRate returns the fraction of the visitors with a visit
+// flagged by MarkConversion
+func (s *Statistics) ConvertedVisitorsRate() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Visitors) == 0 {
+		return 0
+	}
+
+	converted := 0
+
+	for _, v := range s.Visitors {
+		if slices.ContainsFunc(v.History, func(vi *Visit) bool { return vi.Converted }) {
+			converted++
+		}
+	}
+
+	return float64(converted) / float64(len(s.Visitors))
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvColumn reads and writes a visitRow field, the columns are named after
+// the json tags
+type csvColumn struct {
+	name string
+	get func(d *visitRow) string
+	set func(d *visitRow, value string) error
+}
+
+func stringColumn(name string, field func(d *visitRow) *string) csvColumn {
+	return csvColumn{
+		name: name,
+		get: func(d *visitRow) string { return *field(d) },
+		set: func(d *visitRow, value string) error {
+			*field(d) = value
+			return nil
+		},
+	}
+}
+
+func intColumn(name string, get func(d *visitRow) int64, set func(d *visitRow, n int64)) csvColumn {
+	return csvColumn{
+		name: name,
+		get: func(d *visitRow) string { return strconv.FormatInt(get(d), 10) },
+		set: func(d *visitRow, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			set(d, n)
+			return err
+		},
+	}
+}
+
+func boolColumn(name string, field func(d *visitRow) *bool) csvColumn {
+	return csvColumn{
+		name: name,
+		get: func(d *visitRow) string { return strconv.FormatBool(*field(d)) },
+		set: func(d *visitRow, value string) (err error) {
+			*field(d), err = strconv.ParseBool(value)
+			return err
+		},
+	}
+}
+
+var csvColumns = []csvColumn{
+	intColumn("id", func(d *visitRow) int64 { return int64(d.ID) }, func(d *visitRow, n int64) { d.ID = int(n) }),
+	{
+		name: "date",
+		get: func(d *visitRow) string { return d.Date.Format(time.RFC3339Nano) },
+		set: func(d *visitRow, value string) (err error) {
+			d.Date, err = time.Parse(time.RFC3339Nano, value)
+			return err
+		},
+	},
+	{
+		name: "type",
+		get: func(d *visitRow) string { return string(d.Type) },
+		set: func(d *visitRow, value string) error {
+			d.Type = PageType(value)
+			return nil
+		},
+	},
+	stringColumn("path", func(d *visitRow) *string { return &d.Path }),
+	stringColumn("concrete_path", func(d *visitRow) *string { return &d.ConcretePath }),
+	stringColumn("visitor_id", func(d *visitRow) *string { return &d.VisitorID }),
+	stringColumn("visitor_ip", func(d *visitRow) *string { return &d.VisitorIP }),
+	stringColumn("visitor_fingerprint", func(d *visitRow) *string { return &d.VisitorFingerprint }),
+	intColumn("loading_time", func(d *visitRow) int64 { return int64(d.LoadingTime) }, func(d *visitRow, n int64) { d.LoadingTime = time.Duration(n) }),
+	intColumn("time_spent", func(d *visitRow) int64 { return int64(d.TimeSpent) }, func(d *visitRow, n int64) { d.TimeSpent = time.Duration(n) }),
+	intColumn("code_issued", func(d *visitRow) int64 { return int64(d.CodeIssued) }, func(d *visitRow, n int64) { d.CodeIssued = int(n) }),
+	stringColumn("content_type", func(d *visitRow) *string { return &d.ContentType }),
+	stringColumn("query", func(d *visitRow) *string { return &d.Query }),
+	stringColumn("referer", func(d *visitRow) *string { return &d.Referer }),
+	stringColumn("user_id", func(d *visitRow) *string { return &d.UserID }),
+	intColumn("request_size", func(d *visitRow) int64 { return d.RequestSize }, func(d *visitRow, n int64) { d.RequestSize = n }),
+	intColumn("response_size", func(d *visitRow) int64 { return d.ResponseSize }, func(d *visitRow, n int64) { d.ResponseSize = n }),
+	stringColumn("user_agent", func(d *visitRow) *string { return &d.UserAgent }),
+	stringColumn("client_class", func(d *visitRow) *string { return &d.ClientClass }),
+	stringColumn("language", func(d *visitRow) *string { return &d.Language }),
+	stringColumn("content_language", func(d *visitRow) *string { return &d.ContentLanguage }),
+	boolColumn("compressed", func(d *visitRow) *bool { return &d.Compressed }),
+	boolColumn("converted", func(d *visitRow) *bool { return &d.Converted }),
+	stringColumn("protocol", func(d *visitRow) *string { return &d.Protocol }),
+	boolColumn("tls", func(d *visitRow) *bool { return &d.TLS }),
+	boolColumn("warmup", func(d *visitRow) *bool { return &d.Warmup }),
+}
+
+// ExportCSV writes the visits as VisitDTO rows ordered by visit ID after a
+// header of the json field names, the durations are in nanoseconds. As with
+// ExportJSONL the rows carry the visitor IP in visitor_ip
+func (s *Statistics) ExportCSV(w io.Writer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	writer := csv.NewWriter(w)
+	record := make([]string, len(csvColumns))
+
+	for i, column := range csvColumns {
+		record[i] = column.name
+	}
+
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+
+	for _, v := range s.storedVisits() {
+		row := v.exportRow()
+
+		for i, column := range csvColumns {
+			record[i] = column.get(&row)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// ImportCSV records the visits written by ExportCSV, the columns are matched
+// by the names of the header so they may be reordered or missing
+func (s *Statistics) ImportCSV(r io.Reader) error {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	columns := make([]*csvColumn, len(header))
+
+	for i, name := range header {
+		for j := range csvColumns {
+			if csvColumns[j].name == name {
+				columns[i] = &csvColumns[j]
+			}
+		}
+	}
+
+	rows := []visitRow{}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		var row visitRow
+
+		for i, value := range record {
+			if columns[i] == nil {
+				continue
+			}
+
+			if err := columns[i].set(&row, value); err != nil {
+				return fmt.Errorf("visit %d: %s: %w", len(rows), columns[i].name, err)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return s.importRows(rows)
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// roundTripFixture returns statistics with visitors keyed by IP and by
+// fingerprint, static and dynamic visits, referers, users and languages
+func roundTripFixture(t *testing.T) *Statistics {
+	s := New(WithVisitorIDSalt("salt"))
+	start := time.Now().Add(-time.Hour)
+	inputs := []VisitInput{}
+
+	for i := 0; i < 60; i++ {
+		input := VisitInput{
+			Date: start.Add(time.Duration(i) * 17 * time.Second),
+			IP: fmt.Sprintf("10.0.0.%d", i%7),
+			Path: fmt.Sprintf("/page/%d", i%5),
+			CodeIssued: []int{http.StatusOK, http.StatusNotFound, http.StatusInternalServerError}[i%3],
+			LoadingTime: time.Duration(i) * time.Millisecond,
+			ContentType: "text/html; charset=utf-8",
+			Referer: "https://example.com/search?q=a,b",
+			UserAgent: "Mozilla/5.0 (X11; Linux x86_64)",
+			AcceptLanguage: []string{"fr-CH, fr;q=0.9, en;q=0.8", "en-US,en;q=0.9", ""}[i%3],
+			ContentEncoding: "gzip",
+		}
+
+		if i%4 == 0 {
+			input.Path = "/app.js"
+		}
+
+		if i%6 == 0 {
+			input.Fingerprint = fmt.Sprintf("session-%d", i%2)
+			input.UserID = "alice"
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	if err := s.Import(inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// assertRoundTrip checks that imported is a valid copy of s
+func assertRoundTrip(t *testing.T, s, imported *Statistics) {
+	t.Helper()
+
+	if err := imported.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if imported.VisitsCount() != s.VisitsCount() || imported.VisitorsCount() != s.VisitorsCount() || imported.PageCount() != s.PageCount() || imported.UserCount() != s.UserCount() {
+		t.Errorf("imported %d visits, %d visitors, %d pages and %d users, want %d, %d, %d and %d",
+			imported.VisitsCount(), imported.VisitorsCount(), imported.PageCount(), imported.UserCount(),
+			s.VisitsCount(), s.VisitorsCount(), s.PageCount(), s.UserCount())
+	}
+
+	if !maps.Equal(imported.LanguagesCount(), s.LanguagesCount()) {
+		t.Errorf("imported the languages %v, want %v", imported.LanguagesCount(), s.LanguagesCount())
+	}
+
+	exported, reexported := &bytes.Buffer{}, &bytes.Buffer{}
+
+	if err := s.ExportJSONL(exported); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := imported.ExportJSONL(reexported); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(exported.Bytes(), reexported.Bytes()) {
+		t.Errorf("the imported visits differ from the exported ones")
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	s := roundTripFixture(t)
+	buffer := &bytes.Buffer{}
+
+	if err := s.ExportCSV(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := New(WithVisitorIDSalt("salt"))
+
+	if err := imported.ImportCSV(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRoundTrip(t, s, imported)
+}
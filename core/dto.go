@@ -0,0 +1,163 @@
+package core
+
+import (
+	"time"
+)
+
+type (
+	VisitDTO struct {
+		ID int `json:"id"`
+		Date time.Time `json:"date"`
+		Type PageType `json:"type"`
+		Path string `json:"path"`
+		ConcretePath string `json:"concrete_path"`
+		VisitorID string `json:"visitor_id"`
+		VisitorFingerprint string `json:"visitor_fingerprint,omitempty"`
+		LoadingTime time.Duration `json:"loading_time"`
+		TimeSpent time.Duration `json:"time_spent"`
+		CodeIssued int `json:"code_issued"`
+		ContentType string `json:"content_type"`
+		Query string `json:"query,omitempty"`
+		Referer string `json:"referer"`
+		UserID string `json:"user_id,omitempty"`
+		RequestSize int64 `json:"request_size"`
+		ResponseSize int64 `json:"response_size"`
+		UserAgent string `json:"user_agent"`
+		ClientClass string `json:"client_class"`
+		Language string `json:"language,omitempty"`
+		ContentLanguage string `json:"content_language,omitempty"`
+		Compressed bool `json:"compressed"`
+		Converted bool `json:"converted"`
+		Protocol string `json:"protocol,omitempty"`
+		TLS bool `json:"tls"`
+		Warmup bool `json:"warmup"`
+	}
+
+	// visitRow is the VisitDTO of the CSV and JSONL exports, the only ones
+	// carrying the visitor IP as the visitors are rebuilt from it on import
+	visitRow struct {
+		VisitDTO
+		VisitorIP string `json:"visitor_ip"`
+	}
+
+	// VisitorSummary identifies the visitor by its opaque ID, not by its IP
+	VisitorSummary struct {
+		ID string `json:"id"`
+		Language string `json:"language"`
+		DynamicVisits int `json:"dynamic_visits"`
+		StaticVisits int `json:"static_visits"`
+		VisitsCount int `json:"visits_count"`
+		LastVisit time.Time `json:"last_visit"`
+	}
+
+	PageSummary struct {
+		Path string `json:"path"`
+		FirstSeen time.Time `json:"first_seen"`
+		VisitsCount int `json:"visits_count"`
+		VisitorsCount int `json:"visitors_count"`
+	}
+)
+
+// ToDTO returns the visit to serve in an API, its visitor is identified by
+// the opaque Visitor.ID and not by its IP
+func (v *Visit) ToDTO() VisitDTO {
+	dto := VisitDTO{
+		ID: v.ID,
+		Date: v.Date,
+		Type: v.Type,
+		LoadingTime: v.LoadingTime,
+		TimeSpent: v.TimeSpent,
+		CodeIssued: v.CodeIssued,
+		ContentType: v.ContentType,
+		ConcretePath: v.ConcretePath,
+		Query: v.Query,
+		Referer: v.Referer,
+		UserID: v.UserID,
+		RequestSize: v.RequestSize,
+		ResponseSize: v.ResponseSize,
+		UserAgent: v.UserAgent,
+		ClientClass: v.ClientClass,
+		Language: v.Language,
+		ContentLanguage: v.ContentLanguage,
+		Compressed: v.Compressed,
+		Converted: v.Converted,
+		Protocol: v.Protocol,
+		TLS: v.TLS,
+		Warmup: v.Warmup,
+	}
+
+	if v.Page != nil {
+		dto.Path = v.Page.Path
+	}
+
+	if v.VisitedBy != nil {
+		dto.VisitorID = v.VisitedBy.ID
+		dto.VisitorFingerprint = v.VisitedBy.Fingerprint
+	}
+
+	return dto
+}
+
+// exportRow returns the VisitDTO of the visit along with its visitor IP
+func (v *Visit) exportRow() visitRow {
+	row := visitRow{VisitDTO: v.ToDTO()}
+
+	if v.VisitedBy != nil {
+		row.VisitorIP = v.VisitedBy.IP
+	}
+
+	return row
+}
+
+// input returns the VisitInput recording the visit again, the fields derived
+// by the statistics (VisitorID, ClientClass...) are left out
+func (d visitRow) input() VisitInput {
+	return VisitInput{
+		ID: d.ID,
+		Date: d.Date,
+		IP: d.VisitorIP,
+		Fingerprint: d.VisitorFingerprint,
+		Path: d.Path,
+		ConcretePath: d.ConcretePath,
+		Type: d.Type,
+		CodeIssued: d.CodeIssued,
+		LoadingTime: d.LoadingTime,
+		ContentType: d.ContentType,
+		ContentLanguage: d.ContentLanguage,
+		Query: d.Query,
+		Referer: d.Referer,
+		UserAgent: d.UserAgent,
+		AcceptLanguage: d.Language,
+		UserID: d.UserID,
+		RequestSize: d.RequestSize,
+		ResponseSize: d.ResponseSize,
+		Converted: d.Converted,
+		Protocol: d.Protocol,
+		TLS: d.TLS,
+	}
+}
+
+func (v *Visitor) ToDTO() VisitorSummary {
+	summary := VisitorSummary{
+		ID: v.ID,
+		Language: v.Language,
+		DynamicVisits: v.DynamicVisits,
+		StaticVisits: v.StaticVisits,
+		VisitsCount: v.VisitsCount(),
+	}
+
+	if len(v.History) > 0 {
+		summary.LastVisit = v.LastVisit().Date
+	}
+
+	return summary
+}
+
+func (p *Page) ToDTO() PageSummary {
+	return PageSummary{
+		Path: p.Path,
+		FirstSeen: p.FirstSeen,
+		VisitsCount: p.VisitsCount(),
+		VisitorsCount: p.VisitorsCount(),
+	}
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestVisitDTOLeavesOutIP(t *testing.T) {
+	s := New()
+	visit := recordPage(s, "192.0.2.7", "/")
+
+	data, err := json.Marshal(visit.ToDTO())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte("192.0.2.7")) {
+		t.Errorf("the VisitDTO leaks the visitor IP: %s", data)
+	}
+
+	// the exports keep it to rebuild the visitors
+	jsonl, csv := &bytes.Buffer{}, &strings.Builder{}
+
+	if err := s.ExportJSONL(jsonl); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ExportCSV(csv); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(jsonl.String(), `"visitor_ip":"192.0.2.7"`) || !strings.Contains(csv.String(), "192.0.2.7") {
+		t.Errorf("the exports lost the visitor IP")
+	}
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"time"
+	"io"
+	"fmt"
+	"strings"
+	"sort"
+	"encoding/json"
+)
+
+var influxTagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// sortedVisitIDs returns the IDs of the visits in ascending order, the caller
+// must hold the mutex
+func (s *Statistics) sortedVisitIDs() []int {
+	ids := make([]int, 0, len(s.Visits))
+
+	for id := range s.Visits {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// storedVisits returns the visits of the store in ascending ID order, the
+// caller must hold the mutex
+func (s *Statistics) storedVisits() []*Visit {
+	visits := s.store.All()
+
+	sort.Slice(visits, func(i, j int) bool {
+		return visits[i].ID < visits[j].ID
+	})
+
+	return visits
+}
+
+// sortedPagePaths returns the page keys in ascending order, the caller must
+// hold the mutex
+func (s *Statistics) sortedPagePaths() []string {
+	paths := make([]string, 0, len(s.Pages))
+
+	for path := range s.Pages {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// sortedVisitorKeys returns the visitor keys in ascending order, the caller
+// must hold the mutex
+func (s *Statistics) sortedVisitorKeys() []string {
+	keys := make([]string, 0, len(s.Visitors))
+
+	for key := range s.Visitors {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// WriteInfluxLineProtocol writes a snapshot of the visits and visitors counts,
+// site-wide and per page, in the InfluxDB line protocol
+func (s *Statistics) WriteInfluxLineProtocol(w io.Writer, timestamp time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ts := timestamp.UnixNano()
+
+	_, err := fmt.Fprintf(w, "statistics visits=%di,visitors=%di %d\n", s.VisitsCount(), s.VisitorsCount(), ts)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range s.sortedPagePaths() {
+		page := s.Pages[path]
+
+		_, err := fmt.Fprintf(w, "statistics_page,path=%s visits=%di,visitors=%di %d\n", influxTagEscaper.Replace(path), page.VisitsCount(), page.VisitorsCount(), ts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportJSONL writes one VisitDTO per line in newline-delimited JSON, ordered
+// by visit ID. Unlike ToDTO the lines carry the visitor IP in visitor_ip for
+// ImportJSONL, keep the file private
+func (s *Statistics) ExportJSONL(w io.Writer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	encoder := json.NewEncoder(w)
+
+	for _, v := range s.storedVisits() {
+		if err := encoder.Encode(v.exportRow()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportJSONL records the visits written by ExportJSONL, see importRows
+func (s *Statistics) ImportJSONL(r io.Reader) error {
+	rows := []visitRow{}
+	decoder := json.NewDecoder(r)
+
+	for {
+		var row visitRow
+
+		if err := decoder.Decode(&row); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("visit %d: %w", len(rows), err)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return s.importRows(rows)
+}
+
+// importRows imports the visits with their IDs, rebuilding their pages and
+// visitors as Import does, then restores the fields Import can't set. The
+// pages visited before an export of aggregates only (PriorVisits) aren't
+// part of the visits so they aren't restored
+func (s *Statistics) importRows(rows []visitRow) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	inputs := make([]VisitInput, 0, len(rows))
+
+	for _, row := range rows {
+		inputs = append(inputs, row.input())
+	}
+
+	if err := s.importVisits(inputs); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		visit, ok := s.Visits[row.ID]
+		if !ok {
+			visit, ok = s.store.Get(row.ID)
+		}
+
+		if ok {
+			visit.TimeSpent = row.TimeSpent
+			visit.Warmup = row.Warmup
+			visit.Compressed = row.Compressed
+			s.store.Add(visit)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJSONLRoundTrip(t *testing.T) {
+	s := roundTripFixture(t)
+	buffer := &bytes.Buffer{}
+
+	if err := s.ExportJSONL(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := New(WithVisitorIDSalt("salt"))
+
+	if err := imported.ImportJSONL(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRoundTrip(t, s, imported)
+}
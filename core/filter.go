@@ -0,0 +1,84 @@
+package core
+
+import (
+	"time"
+	"slices"
+	"cmp"
+)
+
+// FilterVisits returns the visits matching the predicate sorted by ID
+func (s *Statistics) FilterVisits(pred func(*Visit) bool) []*Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visits := []*Visit{}
+
+	for _, v := range s.Visits {
+		if pred(v) {
+			visits = append(visits, v)
+		}
+	}
+
+	slices.SortFunc(visits, func(a, b *Visit) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return detachVisits(visits)
+}
+
+func ByPath(path string) func(*Visit) bool {
+	return func(v *Visit) bool {
+		return v.Page != nil && v.Page.Path == path
+	}
+}
+
+// ByStatusClass matches the visits whose status code is in the class, e.g. 4
+// for 4xx
+func ByStatusClass(class int) func(*Visit) bool {
+	return func(v *Visit) bool {
+		return v.CodeIssued/100 == class
+	}
+}
+
+// ByTimeRange matches the visits whose date is in [start, end)
+func ByTimeRange(start, end time.Time) func(*Visit) bool {
+	return func(v *Visit) bool {
+		return !v.Date.Before(start) && v.Date.Before(end)
+	}
+}
+
+func ByType(pageType PageType) func(*Visit) bool {
+	return func(v *Visit) bool {
+		return v.Type == pageType
+	}
+}
+
+func And(preds ...func(*Visit) bool) func(*Visit) bool {
+	return func(v *Visit) bool {
+		for _, pred := range preds {
+			if !pred(v) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+func Or(preds ...func(*Visit) bool) func(*Visit) bool {
+	return func(v *Visit) bool {
+		for _, pred := range preds {
+			if pred(v) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func Not(pred func(*Visit) bool) func(*Visit) bool {
+	return func(v *Visit) bool {
+		return !pred(v)
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"time"
+)
+
+type (
+	// MetricsFlusher receives the aggregates of each interval set by
+	// WithMetricsFlusher, e.g. to push them to a time-series database
+	MetricsFlusher interface {
+		Flush(metrics IntervalMetrics) error
+	}
+
+	IntervalMetrics struct {
+		Start time.Time
+		End time.Time
+		Visits int
+		Visitors int
+		AverageLoadingTime time.Duration
+	}
+
+	// NoopFlusher is a MetricsFlusher discarding the metrics
+	NoopFlusher struct{}
+)
+
+func (NoopFlusher) Flush(IntervalMetrics) error {
+	return nil
+}
+
+// startFlusher flushes the metrics of the visits recorded since the previous
+// flush every flushInterval and a last time on Close
+func (s *Statistics) startFlusher() {
+	start := time.Now()
+
+	flush := func() error {
+		s.mutex.Lock()
+		end := time.Now()
+		visits := s.visitsBetween(start, end)
+
+		metrics := IntervalMetrics{
+			Start: start,
+			End: end,
+			Visits: len(visits),
+			Visitors: uniqueVisitors(visits),
+		}
+
+		samples := 0
+
+		for _, v := range visits {
+			if v.isLoadingTimeSample() {
+				samples++
+				metrics.AverageLoadingTime += v.LoadingTime
+			}
+		}
+
+		if samples > 0 {
+			metrics.AverageLoadingTime /= time.Duration(samples)
+		}
+
+		s.mutex.Unlock()
+
+		start = end
+
+		if err := s.flusher.Flush(metrics); err != nil {
+			return err
+		}
+
+		if s.dropFlushedVisits {
+			s.mutex.Lock()
+
+			for _, v := range visits {
+				// the visit may have been removed meanwhile, or offloaded to
+				// the store of WithVisitStore
+				if visit, ok := s.Visits[v.ID]; ok {
+					s.removeVisit(visit)
+				} else {
+					s.store.Remove(v)
+				}
+			}
+
+			s.mutex.Unlock()
+		}
+
+		return nil
+	}
+
+	s.background(func(done <-chan struct{}) {
+		if s.flushInterval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := flush(); err != nil {
+					s.errorLogger().Error("statistics: metrics flush failed", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	})
+
+	s.onClose(flush)
+}
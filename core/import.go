@@ -0,0 +1,127 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// VisitInput describes a visit to record, Record takes one per request and
+// Import pre-computed ones (e.g. from access logs)
+type VisitInput struct {
+	// ID is allocated when 0, see ReserveVisitID
+	ID int
+	// Date is now when zero, Import requires it
+	Date time.Time
+	IP string
+	Fingerprint string
+	// Path is the page key, the route for Record which takes an empty one for
+	// a request matching no route
+	Path string
+	// ConcretePath is the requested path, Path when empty
+	ConcretePath string
+	// Type is, when empty for Import, deduced from ConcretePath and
+	// ContentType as by Record, or without a ContentType Static for the
+	// static extensions and Dynamic otherwise
+	Type PageType
+	CodeIssued int
+	LoadingTime time.Duration
+	ContentType string
+	ContentLanguage string
+	ContentEncoding string
+	Query string
+	Referer string
+	UserAgent string
+	AcceptLanguage string
+	UserID string
+	RequestSize int64
+	ResponseSize int64
+	Converted bool
+	Protocol string
+	TLS bool
+}
+
+// Import records the visits with their original dates, in chronological order
+// so the time spent on pages is computed as by the Middleware. The visits are
+// filtered by WithDynamicOnly, WithIgnoredStatusCodes and WithSuccessfulOnly
+// and their query strings dropped without WithQueryStrings, but they don't
+// trigger the WithOnVisit callbacks. Nothing is recorded if a visit is invalid
+func (s *Statistics) Import(visits []VisitInput) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.importVisits(visits)
+}
+
+func (s *Statistics) importVisits(visits []VisitInput) error {
+	inputs := make([]VisitInput, 0, len(visits))
+	ids := make(map[int]bool)
+
+	for i, input := range visits {
+		if input.Date.IsZero() {
+			return fmt.Errorf("visit %d: missing date", i)
+		}
+
+		if input.Path == "" {
+			return fmt.Errorf("visit %d: missing path", i)
+		}
+
+		if input.IP == "" && input.Fingerprint == "" {
+			return fmt.Errorf("visit %d: missing IP and fingerprint", i)
+		}
+
+		if input.ID < 0 {
+			return fmt.Errorf("visit %d: negative ID %d", i, input.ID)
+		}
+
+		if input.ID > 0 {
+			if _, ok := s.Visits[input.ID]; ok || ids[input.ID] {
+				return fmt.Errorf("visit %d: ID %d already used", i, input.ID)
+			}
+
+			ids[input.ID] = true
+		}
+
+		if input.ConcretePath == "" {
+			input.ConcretePath = input.Path
+		}
+
+		// typed from the requested path as by Record, an access log has no
+		// Content-Type so its visits are only told apart by extension
+		if input.Type == "" && input.ContentType != "" {
+			input.Type = s.pageType(input.ConcretePath, input.ContentType)
+		} else if input.Type == "" && hasAnySuffix(strings.ToLower(input.ConcretePath), s.staticExtensions...) {
+			input.Type = Static
+		} else if input.Type == "" {
+			input.Type = Dynamic
+		}
+
+		if !s.recordsStatus(input.CodeIssued) || (s.dynamicOnly && input.Type == Static) {
+			continue
+		}
+
+		input.IP = normalizeIP(input.IP)
+
+		if !s.queryStrings {
+			input.Query = ""
+		}
+
+		inputs = append(inputs, input)
+	}
+
+	slices.SortStableFunc(inputs, func(a, b VisitInput) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	// the allocated IDs must not collide with the imported ones
+	for id := range ids {
+		s.raiseVisitID(id)
+	}
+
+	for _, input := range inputs {
+		s.record(input)
+	}
+
+	return nil
+}
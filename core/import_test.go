@@ -0,0 +1,57 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const queryLog = `10.0.0.1 - - [10/Oct/2024:13:55:36 +0000] "GET /reset?token=secret HTTP/1.1" 200 512`
+
+func TestImportDropsQueryStrings(t *testing.T) {
+	inputs, err := ParseAccessLog(strings.NewReader(queryLog), AccessLogCommon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New()
+
+	if err := s.Import(inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	if query := s.GetVisit(1).Query; query != "" {
+		t.Errorf("the query string %q was imported without WithQueryStrings", query)
+	}
+
+	s = New(WithQueryStrings())
+
+	if err := s.Import(inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	if query := s.GetVisit(1).Query; query != "token=secret" {
+		t.Errorf("Query = %q with WithQueryStrings, want token=secret", query)
+	}
+}
+
+func TestImportTypesByConcretePath(t *testing.T) {
+	s := New()
+	date := time.Now().Add(-time.Hour)
+
+	err := s.Import([]VisitInput{
+		{ID: 1, Date: date, IP: "10.0.0.1", Path: "/assets/*filepath", ConcretePath: "/assets/app.js"},
+		{ID: 2, Date: date, IP: "10.0.0.1", Path: "/user/:id", ConcretePath: "/user/42", ContentType: "application/json"},
+		{ID: 3, Date: date, IP: "10.0.0.1", Path: "/user/:id", ConcretePath: "/user/42", ContentType: "text/html; charset=utf-8"},
+		{ID: 4, Date: date, IP: "10.0.0.1", Path: "/about"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[int]PageType{1: Static, 2: Static, 3: Dynamic, 4: Dynamic} {
+		if got := s.GetVisit(id).Type; got != want {
+			t.Errorf("visit %d is %s, want %s", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"errors"
+)
+
+// background runs f in a goroutine tracked by Close, f must return once done
+// is closed
+func (s *Statistics) background(f func(done <-chan struct{})) {
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		f(s.done)
+	}()
+}
+
+// onClose registers a flush run by Close once the background goroutines have
+// stopped
+func (s *Statistics) onClose(flush func() error) {
+	s.flushes = append(s.flushes, flush)
+}
+
+// Close stops the background goroutines (autosave...) then flushes their
+// pending work, calling it again returns the same error
+func (s *Statistics) Close() error {
+	s.closeOnce.Do(func() {
+		if s.done != nil {
+			close(s.done)
+		}
+
+		s.wg.Wait()
+
+		errs := []error{}
+
+		for _, flush := range s.flushes {
+			errs = append(errs, flush())
+		}
+
+		s.closeErr = errors.Join(errs...)
+	})
+
+	return s.closeErr
+}
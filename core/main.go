@@ -0,0 +1,2904 @@
+package core
+
+import (
+	"time"
+	"sync"
+	"sync/atomic"
+	"slices"
+	"maps"
+	"cmp"
+	"fmt"
+	"strings"
+	"math"
+	"net/url"
+	"mime"
+	"net"
+	"net/netip"
+	"net/http"
+	"log/slog"
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type (
+	Statistics struct {
+		Visitors map[string]*Visitor
+		Pages map[string]*Page
+		Visits map[int]*Visit
+		// VisitorsLanguage counts each language tag declared by the visitors
+		// (see Visitor.Languages) so a visitor may be counted several times,
+		// with WithPrimaryLanguageOnly it counts the primary language of each
+		// visitor once instead
+		VisitorsLanguage map[string]int
+		Users map[string][]*Visit
+
+		// currentVisitID is atomic so ReserveVisitID doesn't take the mutex
+		currentVisitID atomic.Int64
+		mutex sync.Mutex
+
+		visitsCount atomic.Int64
+		visitorsCount atomic.Int64
+		importedVisits int
+		importedVisitors int
+		removedVisits int
+		createdAt time.Time
+
+		// store owns the visit details, see VisitStore
+		store VisitStore
+		// leastVisited holds the pages WithMaxPages evicts first
+		leastVisited pagesHeap
+		segments map[string]func(*Visitor) bool
+
+		// done is closed by Close to stop the background goroutines
+		done chan struct{}
+		wg sync.WaitGroup
+		flushes []func() error
+		closeOnce sync.Once
+		closeErr error
+
+		settings
+	}
+
+	// settings holds the configuration set by the options
+	settings struct {
+		trackOnlyMatchedRoutes bool
+		dynamicOnly bool
+		refererBlocklist map[string]bool
+		maxPages int
+		onVisit []func(*Visit)
+		withoutTimeSpent bool
+		refererHostOnly bool
+		maxHistoryPerVisitor int
+		logger *slog.Logger
+		staticExtensions []string
+		appUserAgents []string
+		primaryLanguageOnly bool
+		warmupPeriod time.Duration
+		ignoredStatusCodes map[int]bool
+		summaryPercentiles []float64
+		successfulOnly bool
+		visitorIDSalt string
+		currentWindowMin time.Duration
+		currentWindowMax time.Duration
+		queryStrings bool
+		flusher MetricsFlusher
+		flushInterval time.Duration
+		dropFlushedVisits bool
+		// offloadVisits keeps only the visits whose time spent is still to be
+		// set in memory, see WithVisitStore
+		offloadVisits bool
+		autosavePath string
+		autosaveInterval time.Duration
+	}
+
+	// The Page and Visitor methods don't lock: the pages and visitors of the
+	// Pages and Visitors maps are updated by the Middleware, so read them from a
+	// Snapshot or from the copies taken under the lock that the Statistics
+	// methods return (GetPage, MostVisitedPages, VisitsBetween...)
+	Page struct {
+		Path string
+		FirstSeen time.Time
+		Visits []*Visit
+		PriorVisits int
+	}
+
+	Visitor struct {
+		// ID is an opaque salted hash of the visitor key, see WithVisitorIDSalt
+		ID string
+		IP string
+		Fingerprint string
+		Language string
+		FirstSeen time.Time
+		DynamicVisits int
+		StaticVisits int
+		History []*Visit
+
+		lastDynamicVisit *Visit
+	}
+
+	Visit struct {
+		ID int
+		Date time.Time
+		Type PageType
+		LoadingTime time.Duration
+		TimeSpent time.Duration
+		CodeIssued int
+		ContentType string
+		// ConcretePath is the requested path, e.g. /user/42 for the /user/:id page
+		ConcretePath string
+		// Query is the raw query string of the URL, recorded WithQueryStrings
+		Query string
+		Referer string
+		RefererSpam bool
+		UserID string
+		// RequestSize is the request body size in bytes, 0 when unknown
+		RequestSize int64
+		// ResponseSize is the response body size in bytes, 0 when unknown
+		ResponseSize int64
+		UserAgent string
+		ClientClass string
+		// Language is the Accept-Language header of the request
+		Language string
+		ContentLanguage string
+		// Compressed is set when the response had a Content-Encoding
+		Compressed bool
+		// Converted is set by MarkConversion
+		Converted bool
+		// Protocol is the protocol of the request, e.g. "HTTP/2.0"
+		Protocol string
+		TLS bool
+		// Warmup is set for the visits made during the warmup period, they are
+		// counted but left out of the loading time metrics
+		Warmup bool
+		VisitedBy *Visitor
+		Page *Page
+	}
+
+	// Cohort holds the visitors first seen during a period, Retention[i] is the
+	// fraction of them that visited during the i-th period after it
+	Cohort struct {
+		Size int
+		Retention []float64
+	}
+
+	TrendPoint struct {
+		Start time.Time
+		Count int
+	}
+
+	JourneyStep struct {
+		Date time.Time
+		Path string
+		TimeSpent time.Duration
+		Entry bool
+		Exit bool
+	}
+
+	Summary struct {
+		VisitsCount int
+		VisitorsCount int
+		EstimatedCurrentVisitors int
+		AverageLoadingTime time.Duration
+		BounceRate float64
+		LoadingTimePercentiles []Percentile
+		TopLanguages []LanguageCount
+		TopPages []PageCount
+	}
+
+	Percentile struct {
+		Percentile float64
+		Value time.Duration
+	}
+
+	PageComparison struct {
+		A PageStats
+		B PageStats
+	}
+
+	// PageStats is the side of a PageComparison, Found is false if the page
+	// has never been visited. Bounces counts the visitors whose only dynamic
+	// visit was to this page
+	PageStats struct {
+		Path string
+		Found bool
+		VisitsCount int
+		VisitorsCount int
+		AverageTimeSpent time.Duration
+		AverageLoadingTime time.Duration
+		Bounces int
+	}
+
+	PageShare struct {
+		Page *Page
+		Count int
+		Share float64
+	}
+
+	// PageTrend compares the visits of a page during the last period with the
+	// period before it, Change is the percentage change as returned by
+	// GrowthRate so pages new this period have a +Inf change
+	PageTrend struct {
+		Page *Page
+		Current int
+		Previous int
+		Change float64
+	}
+
+	LanguageCount struct {
+		Language string
+		Count int
+	}
+
+	PageCount struct {
+		Path string
+		Count int
+	}
+
+	// PageScore is a page co-visited with the one given to PageAffinity,
+	// Visitors is the number of visitors of both and Score the share of the
+	// visitors of the given page they make up, from 0 to 1
+	PageScore struct {
+		Path string
+		Visitors int
+		Score float64
+	}
+
+	// visitsHeap merges time-ordered visit slices, each entry being the rest of
+	// a slice still to merge
+	visitsHeap [][]*Visit
+
+	// pagesHeap orders the pages by VisitsCount then path. An entry is pushed
+	// when a page is added or loses a visit and checked when popped, so the
+	// visits added to the pages don't have to update it
+	pagesHeap []pageEntry
+
+	// pageEntry is a page of a pagesHeap with its VisitsCount when pushed
+	pageEntry struct {
+		page *Page
+		count int
+	}
+
+	pagesSlice []*Page
+
+	PageType string
+)
+
+// Dynamic and Static are the built-in page types, any other PageType can be
+// set through the "PageType" context key
+const (
+	Dynamic PageType = "route"
+	Static PageType = "static"
+
+	UnmatchedPath = "(unmatched)"
+	OtherPath = "(other)"
+
+	StatusHijacked = 0
+
+	MetricVisits = "visits"
+	MetricVisitors = "visitors"
+
+	ClientBrowser = "browser"
+	ClientApp = "app"
+	ClientBot = "bot"
+	ClientOther = "other"
+
+	UnknownLanguage = "unknown"
+	DirectTraffic = "(direct)"
+
+	summaryTopCount = 10
+	scannerMinVisits = 10
+	regressionMinSamples = 10
+)
+
+var defaultStaticExtensions = []string{
+	".css", ".js", ".mjs", ".map",
+	".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".avif", ".ico",
+	".woff", ".woff2", ".ttf", ".otf", ".eot",
+	".mp4", ".webm", ".mp3", ".pdf", ".zip", ".txt", ".xml", ".wasm",
+}
+
+// the user agent patterns are lowercase
+var botUserAgents = []string{
+	"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests",
+	"go-http-client", "headless", "scrapy", "httpclient", "java/",
+}
+
+var defaultAppUserAgents = []string{
+	"okhttp", "cfnetwork", "dalvik", "alamofire", "dart:io", "reactnative",
+}
+
+var defaultRefererBlocklist = []string{
+	"semalt.com",
+	"buttons-for-website.com",
+	"best-seo-offer.com",
+	"darodar.com",
+	"ilovevitaly.com",
+	"priceg.com",
+	"hulfingtonpost.com",
+	"free-social-buttons.com",
+}
+
+func New(options ...Option) *Statistics {
+	s := &Statistics{
+		Pages: make(map[string]*Page),
+		Visitors: make(map[string]*Visitor),
+		Visits: make(map[int]*Visit),
+		VisitorsLanguage: make(map[string]int),
+		Users: make(map[string][]*Visit),
+		createdAt: time.Now(),
+		store: NewMemoryVisitStore(),
+		done: make(chan struct{}),
+		settings: settings{
+			refererBlocklist: make(map[string]bool),
+			staticExtensions: slices.Clone(defaultStaticExtensions),
+			appUserAgents: slices.Clone(defaultAppUserAgents),
+			ignoredStatusCodes: make(map[int]bool),
+			summaryPercentiles: []float64{50, 90, 99},
+			currentWindowMin: 30 * time.Second,
+			currentWindowMax: 30 * time.Minute,
+		},
+	}
+
+	for _, host := range defaultRefererBlocklist {
+		s.refererBlocklist[host] = true
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	if s.visitorIDSalt == "" {
+		salt := make([]byte, 16)
+		rand.Read(salt)
+		s.visitorIDSalt = hex.EncodeToString(salt)
+	}
+
+	// the flusher runs first on Close so the last autosave follows the drop of
+	// the flushed visits
+	if s.flusher != nil {
+		s.startFlusher()
+	}
+
+	if s.autosavePath != "" {
+		s.startAutosave()
+	}
+
+	return s
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, substr := range substrings {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// refererHost returns the lowercased host of the referer without its port, or
+// an empty string if the referer is empty or invalid
+func refererHost(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+// refererOrigin returns the scheme and host of the referer, dropping its path
+// and query, e.g. "https://x.com" for "https://x.com/secret?t=abc"
+func refererOrigin(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String()
+}
+
+// clientClass classifies the user agent as ClientBot, ClientApp (native
+// apps and SDKs), ClientBrowser or ClientOther, in that order of precedence
+func (s *Statistics) clientClass(userAgent string) string {
+	userAgent = strings.ToLower(userAgent)
+
+	switch {
+	case containsAny(userAgent, botUserAgents...):
+		return ClientBot
+	case containsAny(userAgent, s.appUserAgents...):
+		return ClientApp
+	case strings.Contains(userAgent, "mozilla/"):
+		return ClientBrowser
+	}
+
+	return ClientOther
+}
+
+func (s *Statistics) isRefererSpam(host string) bool {
+	for host != "" {
+		if s.refererBlocklist[host] {
+			return true
+		}
+
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+
+		host = parent
+	}
+
+	return false
+}
+
+// normalizeIP strips the port and the zone of the address and unmaps the
+// IPv4-mapped IPv6 addresses so the same client always maps to the same
+// visitor, the raw value (e.g. a fingerprint) is returned if it isn't an IP
+func normalizeIP(ip string) string {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	if err != nil {
+		return ip
+	}
+
+	return addr.WithZone("").Unmap().String()
+}
+
+// declaredLanguages returns the lowercased language tags of the comma
+// separated Accept-Language header without their weights, e.g. "fr-ch", "fr"
+// and "en" for "fr-CH, fr;q=0.9, en;q=0.8". The "*" wildcard isn't a language
+func declaredLanguages(header string) []string {
+	languages := []string{}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		tag = strings.TrimSpace(tag)
+
+		if tag != "" && tag != "*" {
+			languages = append(languages, strings.ToLower(tag))
+		}
+	}
+
+	return languages
+}
+
+// primaryLanguage returns the primary subtag of the first language of the
+// Accept-Language header, e.g. "fr" for "fr-CH, fr;q=0.9, en;q=0.8", or
+// UnknownLanguage if there isn't any
+func primaryLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	first, _, _ = strings.Cut(first, ";")
+	first, _, _ = strings.Cut(strings.TrimSpace(first), "-")
+
+	if first == "" || first == "*" {
+		return UnknownLanguage
+	}
+
+	return strings.ToLower(first)
+}
+
+// countedLanguages returns the languages of the header counted in
+// VisitorsLanguage according to the language counting mode, the same tags as
+// Visitor.Languages without WithPrimaryLanguageOnly
+func (s *Statistics) countedLanguages(header string) []string {
+	if s.primaryLanguageOnly {
+		return []string{primaryLanguage(header)}
+	}
+
+	return declaredLanguages(header)
+}
+
+// Record records a request once it has been answered, it is what the gin
+// Middleware of statistics/ginstats calls and can back a middleware for any
+// other router. An empty input.Path stands for a request matching no route and
+// an empty input.Type is deduced from the path extension then from the
+// Content-Type. It returns a copy of the recorded visit as GetVisit does, or
+// false for the requests filtered out by the options
+func (s *Statistics) Record(ctx context.Context, input VisitInput) (*Visit, bool) {
+	if !s.recordsStatus(input.CodeIssued) {
+		return nil, false
+	}
+
+	if input.ConcretePath == "" {
+		input.ConcretePath = input.Path
+	}
+
+	if input.Type == "" {
+		input.Type = s.pageType(input.ConcretePath, input.ContentType)
+	}
+
+	if s.dynamicOnly && input.Type == Static {
+		return nil, false
+	}
+
+	// the pages are keyed by route (/user/:id) to bound their number, the
+	// requested path is kept in Visit.ConcretePath
+	if input.Path == "" && s.trackOnlyMatchedRoutes {
+		input.Path = UnmatchedPath
+	} else if input.Path == "" {
+		input.Path = input.ConcretePath
+	}
+
+	input.IP = normalizeIP(input.IP)
+
+	if !s.queryStrings {
+		input.Query = ""
+	}
+
+	s.mutex.Lock()
+	visit := detachVisit(s.record(input))
+	s.mutex.Unlock()
+
+	if s.logger != nil {
+		s.logger.LogAttrs(ctx, slog.LevelDebug, "visit",
+			slog.String("path", input.Path),
+			slog.Int("status", visit.CodeIssued),
+			slog.Duration("duration", visit.LoadingTime),
+			slog.String("ip", input.IP),
+			slog.String("type", string(visit.Type)),
+		)
+	}
+
+	for _, onVisit := range s.onVisit {
+		onVisit(visit)
+	}
+
+	return visit, true
+}
+
+// pageType classifies a visit without an explicit PageType: Static for the
+// static extensions, Dynamic for an html response and Static otherwise
+func (s *Statistics) pageType(path, contentType string) PageType {
+	if hasAnySuffix(strings.ToLower(path), s.staticExtensions...) {
+		return Static
+	}
+
+	if strings.Contains(contentType, "text/html") {
+		return Dynamic
+	}
+
+	return Static
+}
+
+// visitorID hashes the visitor key with the salt so the IDs can't be traced
+// back to the IPs without it
+func (s *Statistics) visitorID(key string) string {
+	hash := sha256.Sum256([]byte(s.visitorIDSalt + key))
+
+	return hex.EncodeToString(hash[:8])
+}
+
+// recordsStatus reports whether visits answered with the code are recorded
+// according to WithIgnoredStatusCodes and WithSuccessfulOnly
+func (s *Statistics) recordsStatus(code int) bool {
+	if s.successfulOnly && (code < 200 || code >= 400) {
+		return false
+	}
+
+	return !s.ignoredStatusCodes[code]
+}
+
+// ReserveVisitID allocates the ID of a request's visit before its handlers
+// run so they can read it, the ID then goes in VisitInput.ID. It returns 0
+// when WithIgnoredStatusCodes, WithSuccessfulOnly or WithDynamicOnly may
+// skip the request: Record allocates the ID instead, so the skipped requests
+// don't consume IDs
+func (s *Statistics) ReserveVisitID() int {
+	if len(s.ignoredStatusCodes) > 0 || s.successfulOnly || s.dynamicOnly {
+		return 0
+	}
+
+	return int(s.currentVisitID.Add(1))
+}
+
+// raiseVisitID makes the IDs allocated from now on follow id
+func (s *Statistics) raiseVisitID(id int) {
+	for {
+		current := s.currentVisitID.Load()
+
+		if current >= int64(id) || s.currentVisitID.CompareAndSwap(current, int64(id)) {
+			return
+		}
+	}
+}
+
+// record adds the visit to the statistics, dated now if input.Date is zero,
+// and allocates its ID unless input.ID is set. It must be called with the
+// mutex locked
+func (s *Statistics) record(input VisitInput) *Visit {
+	visitID := input.ID
+
+	if visitID == 0 {
+		visitID = int(s.currentVisitID.Add(1))
+	} else {
+		s.raiseVisitID(visitID)
+	}
+
+	date := input.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	visitorKey := input.IP
+	if input.Fingerprint != "" {
+		visitorKey = input.Fingerprint
+	}
+
+	if _, ok := s.Pages[input.Path]; !ok {
+		if s.maxPages > 0 {
+			s.evictPages(s.maxPages - 1)
+		}
+
+		s.Pages[input.Path] = &Page{
+			Path: input.Path,
+		}
+		s.trackPage(s.Pages[input.Path])
+	}
+
+	if _, ok:= s.Visitors[visitorKey]; !ok {
+		s.Visitors[visitorKey] = &Visitor{
+			ID: s.visitorID(visitorKey),
+			IP: input.IP,
+			Fingerprint: input.Fingerprint,
+			Language: input.AcceptLanguage,
+		}
+
+		for _, l := range s.countedLanguages(input.AcceptLanguage) {
+			s.VisitorsLanguage[l] = s.VisitorsLanguage[l] + 1
+		}
+
+		s.visitorsCount.Add(1)
+
+	}
+
+	visitor := s.Visitors[visitorKey]
+	page := s.Pages[input.Path]
+
+	// an imported visit older than the last dynamic one doesn't end it
+	latest := visitor.lastDynamicVisit == nil || !date.Before(visitor.lastDynamicVisit.Date)
+
+	// counted by the totals as WithVisitStore keeps only the last dynamic visit
+	// in the history
+	if last := visitor.lastDynamicVisit; !s.withoutTimeSpent && visitor.DynamicVisits+visitor.StaticVisits > 1 && last != nil && latest {
+		last.TimeSpent = date.Sub(last.Date)
+		s.store.Add(last)
+	}
+
+	if input.Type == Dynamic { visitor.DynamicVisits += 1 }
+	if input.Type == Static { visitor.StaticVisits += 1 }
+	
+
+	referer := input.Referer
+
+	if s.refererHostOnly {
+		referer = refererOrigin(referer)
+	}
+
+	visit := &Visit{
+		ID: visitID,
+		Type: input.Type,
+		Date: date,
+		TimeSpent: 0,
+		Referer: referer,
+		RefererSpam: s.isRefererSpam(refererHost(referer)),
+		ContentType: input.ContentType,
+		ConcretePath: input.ConcretePath,
+		Query: input.Query,
+		CodeIssued: input.CodeIssued,
+		LoadingTime: input.LoadingTime,
+		RequestSize: input.RequestSize,
+		ResponseSize: input.ResponseSize,
+		UserID: input.UserID,
+		VisitedBy: visitor,
+		Page: page,
+	}
+
+	if visit.ConcretePath == "" {
+		visit.ConcretePath = input.Path
+	}
+
+	// visits dated before New, i.e. imported ones, aren't warmup visits
+	warmupAge := visit.Date.Sub(s.createdAt)
+	visit.Warmup = warmupAge >= 0 && warmupAge < s.warmupPeriod
+	visit.Language = input.AcceptLanguage
+	visit.ContentLanguage = input.ContentLanguage
+	visit.Compressed = input.ContentEncoding != "" && input.ContentEncoding != "identity"
+	visit.Converted = input.Converted
+	visit.Protocol = input.Protocol
+	visit.TLS = input.TLS
+	visit.UserAgent = input.UserAgent
+	visit.ClientClass = s.clientClass(visit.UserAgent)
+
+	if page.FirstSeen.IsZero() || visit.Date.Before(page.FirstSeen) {
+		page.FirstSeen = visit.Date
+	}
+
+	if visitor.FirstSeen.IsZero() || visit.Date.Before(visitor.FirstSeen) {
+		visitor.FirstSeen = visit.Date
+	}
+
+	page.Visits = insertByDate(page.Visits, visit)
+	visitor.History = insertByDate(visitor.History, visit)
+	s.Visits[visitID] = visit
+	s.store.Add(visit)
+
+	if input.Type == Dynamic && latest {
+		visitor.lastDynamicVisit = visit
+	}
+
+	if visit.UserID != "" {
+		s.Users[visit.UserID] = insertByDate(s.Users[visit.UserID], visit)
+	}
+
+	// trimmed once indexed everywhere, the visit itself can be the oldest one
+	if s.maxHistoryPerVisitor > 0 {
+		for len(visitor.History) > s.maxHistoryPerVisitor {
+			s.removeVisit(visitor.History[0])
+		}
+	}
+
+	if s.offloadVisits {
+		s.offload(visitor)
+	}
+
+	s.visitsCount.Add(1)
+
+	return visit
+}
+
+// evictPages moves the visits of the least visited pages to the OtherPath page
+// until there are at most max pages besides it
+func (s *Statistics) evictPages(max int) {
+	other, hasOther := s.Pages[OtherPath]
+
+	count := len(s.Pages)
+	if hasOther {
+		count--
+	}
+
+	for ; count > max; count-- {
+		least := s.popLeastVisited()
+
+		if other == nil {
+			other = &Page{
+				Path: OtherPath,
+			}
+			s.Pages[OtherPath] = other
+		}
+
+		for _, v := range least.Visits {
+			v.Page = other
+			s.store.Add(v)
+		}
+
+		other.Visits = mergeByDate(other.Visits, least.Visits)
+		other.PriorVisits += least.PriorVisits
+
+		if len(other.Visits) > 0 {
+			other.FirstSeen = other.Visits[0].Date
+		}
+
+		delete(s.Pages, least.Path)
+	}
+}
+
+// trackPage makes the page, already in the Pages map, a candidate for the
+// eviction of WithMaxPages
+func (s *Statistics) trackPage(page *Page) {
+	if s.maxPages <= 0 || page.Path == OtherPath {
+		return
+	}
+
+	// the stale entries are dropped once they outnumber the pages
+	if len(s.leastVisited) >= 2*len(s.Pages) {
+		s.rebuildLeastVisited()
+		return
+	}
+
+	heap.Push(&s.leastVisited, pageEntry{page: page, count: page.VisitsCount()})
+}
+
+func (s *Statistics) rebuildLeastVisited() {
+	s.leastVisited = s.leastVisited[:0]
+
+	for path, page := range s.Pages {
+		if path != OtherPath {
+			s.leastVisited = append(s.leastVisited, pageEntry{page: page, count: page.VisitsCount()})
+		}
+	}
+
+	heap.Init(&s.leastVisited)
+}
+
+// popLeastVisited removes the least visited page, the one with the smallest
+// path among equals, from the eviction candidates. Each page has an entry with
+// at most its current count, so an up to date entry on top is the least
+// visited page
+func (s *Statistics) popLeastVisited() *Page {
+	for {
+		if len(s.leastVisited) == 0 {
+			s.rebuildLeastVisited()
+		}
+
+		entry := heap.Pop(&s.leastVisited).(pageEntry)
+		count := entry.page.VisitsCount()
+
+		switch {
+		case s.Pages[entry.page.Path] != entry.page || entry.count > count:
+			// evicted, or superseded by the entry pushed when it lost a visit
+		case entry.count < count:
+			heap.Push(&s.leastVisited, pageEntry{page: entry.page, count: count})
+		default:
+			return entry.page
+		}
+	}
+}
+
+// removeVisit removes the visit from every structure referencing it, the
+// visitors counters and the VisitsCount total are left untouched
+func (s *Statistics) removeVisit(visit *Visit) {
+	s.unlinkVisit(visit)
+	s.store.Remove(visit)
+}
+
+// offload drops from memory the visits of the visitor but its last dynamic
+// one, the store keeps them and the page still counts them
+func (s *Statistics) offload(visitor *Visitor) {
+	for _, v := range slices.Clone(visitor.History) {
+		if v != visitor.lastDynamicVisit {
+			v.Page.PriorVisits++
+			s.unlinkVisit(v)
+		}
+	}
+}
+
+// unlinkVisit removes the visit from the Visits map, its page, its visitor
+// and its user
+func (s *Statistics) unlinkVisit(visit *Visit) {
+	removeFrom := func(visits []*Visit) []*Visit {
+		if i := indexByDate(visits, visit); i >= 0 {
+			return slices.Delete(visits, i, i+1)
+		}
+
+		return visits
+	}
+
+	visit.VisitedBy.History = removeFrom(visit.VisitedBy.History)
+	visit.Page.Visits = removeFrom(visit.Page.Visits)
+	s.trackPage(visit.Page)
+
+	if visit.UserID != "" {
+		s.Users[visit.UserID] = removeFrom(s.Users[visit.UserID])
+
+		if len(s.Users[visit.UserID]) == 0 {
+			delete(s.Users, visit.UserID)
+		}
+	}
+
+	if visit.VisitedBy.lastDynamicVisit == visit {
+		visit.VisitedBy.lastDynamicVisit = nil
+	}
+
+	delete(s.Visits, visit.ID)
+	s.removedVisits++
+}
+
+// MergeVisitors folds the visitor keyed by secondary (its IP, or fingerprint
+// see SetFingerprint) into the one keyed by primary, for a person known to
+// have used both
+func (s *Statistics) MergeVisitors(primary, secondary string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	primary, secondary = normalizeIP(primary), normalizeIP(secondary)
+
+	if primary == secondary {
+		return fmt.Errorf("can't merge a visitor into itself")
+	}
+
+	into, ok := s.Visitors[primary]
+	if !ok {
+		return fmt.Errorf("visitor %s not found", primary)
+	}
+
+	from, ok := s.Visitors[secondary]
+	if !ok {
+		return fmt.Errorf("visitor %s not found", secondary)
+	}
+
+	for _, v := range from.History {
+		v.VisitedBy = into
+		s.store.Add(v)
+	}
+
+	into.History = append(into.History, from.History...)
+
+	slices.SortStableFunc(into.History, func(a, b *Visit) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	into.DynamicVisits += from.DynamicVisits
+	into.StaticVisits += from.StaticVisits
+
+	if into.FirstSeen.IsZero() || (!from.FirstSeen.IsZero() && from.FirstSeen.Before(into.FirstSeen)) {
+		into.FirstSeen = from.FirstSeen
+	}
+
+	if into.lastDynamicVisit == nil || (from.lastDynamicVisit != nil && from.lastDynamicVisit.Date.After(into.lastDynamicVisit.Date)) {
+		into.lastDynamicVisit = from.lastDynamicVisit
+	}
+
+	for _, l := range s.countedLanguages(from.Language) {
+		s.VisitorsLanguage[l]--
+
+		if s.VisitorsLanguage[l] <= 0 {
+			delete(s.VisitorsLanguage, l)
+		}
+	}
+
+	delete(s.Visitors, secondary)
+	s.visitorsCount.Add(-1)
+
+	if s.maxHistoryPerVisitor > 0 {
+		for len(into.History) > s.maxHistoryPerVisitor {
+			s.removeVisit(into.History[0])
+		}
+	}
+
+	if s.offloadVisits {
+		s.offload(into)
+	}
+
+	return nil
+}
+
+// GetPage returns a copy of the page taken under the lock, the VisitedBy of its
+// visits are copies of the visitors without their history
+func (s *Statistics) GetPage(path string) *Page {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if page, ok := s.Pages[path]; ok {
+		return detachPage(page)
+	}
+
+	return &Page{}
+}
+
+// GetVisitor returns a copy of the visitor taken under the lock, the Page of
+// its visits are copies of the pages without their visits. Like the other
+// methods taking an ip, it normalizes it as the Middleware does (see
+// normalizeIP) so "[::1]:80" finds the visitor of "::1"
+func (s *Statistics) GetVisitor(ip string) *Visitor {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if visitor, ok := s.Visitors[normalizeIP(ip)]; ok {
+		return detachVisitor(visitor)
+	}
+
+	return &Visitor{}
+}
+
+// GetVisit returns a copy of the visit taken under the lock, its VisitedBy and
+// Page are copies without their history and visits
+func (s *Statistics) GetVisit(id int) *Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if visit, ok := s.store.Get(id); ok {
+		return s.detachStored([]*Visit{visit})[0]
+	}
+
+	return &Visit{}
+}
+
+// VisitorJourney returns the dynamic visits of the visitor in the order they
+// happened, the first step being the entry and the last one the exit.
+func (s *Statistics) VisitorJourney(ip string) []JourneyStep {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	journey := []JourneyStep{}
+
+	visitor, ok := s.Visitors[normalizeIP(ip)]
+	if !ok {
+		return journey
+	}
+
+	for _, v := range visitor.History {
+		if v.Type != Dynamic {
+			continue
+		}
+
+		journey = append(journey, JourneyStep{
+			Date: v.Date,
+			Path: v.Page.Path,
+			TimeSpent: v.TimeSpent,
+		})
+	}
+
+	if len(journey) > 0 {
+		journey[0].Entry = true
+		journey[len(journey)-1].Exit = true
+	}
+
+	return journey
+}
+
+func (s *Statistics) VisitsByUser(userID string) []*Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return detachVisits(s.Users[userID])
+}
+
+func (s *Statistics) UserCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.Users)
+}
+
+// VisitsCount and VisitorsCount read atomic counters maintained by the
+// Middleware so they don't contend with it for the mutex
+func (s *Statistics) VisitsCount() int {
+	return int(s.visitsCount.Load())
+}
+
+func (s *Statistics) VisitorsCount() int {
+	return int(s.visitorsCount.Load())
+}
+
+// CountersConsistent reports whether the atomic counters match the lengths of
+// the Visits and Visitors maps plus the imported aggregates and removed visits
+func (s *Statistics) CountersConsistent() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.VisitsCount() == len(s.Visits)+s.importedVisits+s.removedVisits && s.VisitorsCount() == len(s.Visitors)+s.importedVisitors
+}
+
+func (s *Statistics) PageCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.Pages)
+}
+
+// RegisterRoutes adds the given paths to the pages with zero visits so
+// unvisited routes show up in LeastVisitedPages
+func (s *Statistics) RegisterRoutes(paths ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, path := range paths {
+		if _, ok := s.Pages[path]; ok {
+			continue
+		}
+
+		if s.maxPages > 0 {
+			s.evictPages(s.maxPages - 1)
+		}
+
+		s.Pages[path] = &Page{
+			Path: path,
+		}
+		s.trackPage(s.Pages[path])
+	}
+}
+
+func (s *Statistics) HasPage(path string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, ok := s.Pages[path]
+
+	return ok
+}
+
+// EstimatedCurrentVisitors counts the visitors whose last dynamic visit is more
+// recent than their average time spent, clamped by WithCurrentVisitorWindow
+func (s *Statistics) EstimatedCurrentVisitors() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.estimatedCurrentVisitors()
+}
+
+func (s *Statistics) estimatedCurrentVisitors() int {
+	estimatedCurrentVisitors := 0
+
+	for _, v := range s.Visitors {
+		// a visitor with a single short page view would otherwise be dropped
+		// right away
+		window := max(v.AverageTimeSpent(), s.currentWindowMin)
+		if s.currentWindowMax > 0 {
+			window = min(window, s.currentWindowMax)
+		}
+
+		if time.Since(v.LastDynamicVisit().Date) < window {
+			estimatedCurrentVisitors++
+		}
+	}
+
+	return estimatedCurrentVisitors
+}
+
+// OnlineNow counts the visitors whose last visit of one of the types (of any
+// type if none is given) is less than ttl old, e.g. OnlineNow(5*time.Minute,
+// Dynamic) for an "online" badge
+func (s *Statistics) OnlineNow(ttl time.Duration, types ...PageType) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	online := make(map[string]bool)
+
+	// the visits dated now are included
+	for _, v := range s.visitsBetween(time.Now().Add(-ttl), time.Now().Add(time.Nanosecond)) {
+		if len(types) == 0 || slices.Contains(types, v.Type) {
+			online[visitorKey(v.VisitedBy)] = true
+		}
+	}
+
+	return len(online)
+}
+
+// MedianLoadingTime returns the median loading time of the dynamic visits,
+// warmup visits excepted
+func (s *Statistics) MedianLoadingTime() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return percentile(sortedLoadingTimes(s.store.All()), 50)
+}
+
+func (s *Statistics) AverageLoadingTime() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.averageLoadingTime()
+}
+
+func (s *Statistics) averageLoadingTime() time.Duration {
+	i := 0
+	totalLoadingTime := time.Duration(0)
+
+	for _, v := range s.Visits {
+		if v.isLoadingTimeSample() {
+			i++
+			totalLoadingTime += v.LoadingTime
+		}
+	}
+
+	if i == 0 {
+		return 0
+	}
+
+	return totalLoadingTime / time.Duration(i)
+}
+
+// BounceRate returns the fraction of the visitors with dynamic visits that
+// left after a single one
+func (s *Statistics) BounceRate() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.bounceRate()
+}
+
+func (s *Statistics) bounceRate() float64 {
+	visitors := 0
+	bounces := 0
+
+	for _, v := range s.Visitors {
+		if v.DynamicVisits > 0 {
+			visitors++
+		}
+
+		if v.DynamicVisits == 1 {
+			bounces++
+		}
+	}
+
+	if visitors == 0 {
+		return 0
+	}
+
+	return float64(bounces) / float64(visitors)
+}
+
+// Summary computes the common statistics under a single lock acquisition so
+// they are consistent with each other
+func (s *Statistics) Summary() Summary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summary := Summary{
+		VisitsCount: s.VisitsCount(),
+		VisitorsCount: s.VisitorsCount(),
+		EstimatedCurrentVisitors: s.estimatedCurrentVisitors(),
+		AverageLoadingTime: s.averageLoadingTime(),
+		BounceRate: s.bounceRate(),
+		LoadingTimePercentiles: []Percentile{},
+		TopLanguages: []LanguageCount{},
+		TopPages: []PageCount{},
+	}
+
+	samples := sortedLoadingTimes(s.store.All())
+
+	for _, p := range s.summaryPercentiles {
+		summary.LoadingTimePercentiles = append(summary.LoadingTimePercentiles, Percentile{
+			Percentile: p,
+			Value: percentile(samples, p),
+		})
+	}
+
+	for language, count := range s.VisitorsLanguage {
+		summary.TopLanguages = append(summary.TopLanguages, LanguageCount{
+			Language: language,
+			Count: count,
+		})
+	}
+
+	slices.SortFunc(summary.TopLanguages, func(a, b LanguageCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Language, b.Language))
+	})
+
+	for path, page := range s.Pages {
+		summary.TopPages = append(summary.TopPages, PageCount{
+			Path: path,
+			Count: page.VisitsCount(),
+		})
+	}
+
+	sortPageCounts(summary.TopPages)
+
+	summary.TopLanguages = summary.TopLanguages[:min(len(summary.TopLanguages), summaryTopCount)]
+	summary.TopPages = summary.TopPages[:min(len(summary.TopPages), summaryTopCount)]
+
+	return summary
+}
+
+func (s *Statistics) ComparePages(pathA, pathB string) PageComparison {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return PageComparison{
+		A: s.pageStats(pathA),
+		B: s.pageStats(pathB),
+	}
+}
+
+func (s *Statistics) pageStats(path string) PageStats {
+	page, ok := s.Pages[path]
+	if !ok {
+		return PageStats{Path: path}
+	}
+
+	stats := PageStats{
+		Path: path,
+		Found: true,
+		VisitsCount: page.VisitsCount(),
+		VisitorsCount: page.VisitorsCount(),
+		AverageTimeSpent: page.AverageTimeSpent(),
+		AverageLoadingTime: page.AverageLoadingTime(),
+	}
+
+	for _, v := range s.Visitors {
+		if v.DynamicVisits == 1 && v.LastDynamicVisit().Page == page {
+			stats.Bounces++
+		}
+	}
+
+	return stats
+}
+
+// sortPageCounts sorts by descending count then by path
+func sortPageCounts(counts []PageCount) {
+	slices.SortFunc(counts, func(a, b PageCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Path, b.Path))
+	})
+}
+
+// LikelyScanners returns the visitors with at least scannerMinVisits visits
+// whose NotFoundRate is above the threshold, by descending rate
+func (s *Statistics) LikelyScanners(threshold float64) []*Visitor {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	scanners := []*Visitor{}
+
+	for _, v := range s.Visitors {
+		if len(v.History) >= scannerMinVisits && v.NotFoundRate() > threshold {
+			scanners = append(scanners, v)
+		}
+	}
+
+	slices.SortFunc(scanners, func(a, b *Visitor) int {
+		return cmp.Or(cmp.Compare(b.NotFoundRate(), a.NotFoundRate()), cmp.Compare(a.IP, b.IP))
+	})
+
+	return detachVisitors(scanners)
+}
+
+// AverageLanguagesPerVisitor returns the mean number of languages declared per
+// visitor, see Visitor.Languages
+func (s *Statistics) AverageLanguagesPerVisitor() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Visitors) == 0 {
+		return 0
+	}
+
+	total := 0
+
+	for _, v := range s.Visitors {
+		total += len(v.Languages())
+	}
+
+	return float64(total) / float64(len(s.Visitors))
+}
+
+// LanguageChangedVisitorsCount counts the visitors whose Accept-Language
+// changed across their visits, see Visitor.LanguageChanged
+func (s *Statistics) LanguageChangedVisitorsCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+
+	for _, v := range s.Visitors {
+		if v.LanguageChanged() {
+			count++
+		}
+	}
+
+	return count
+}
+
+// VisitorSummaries returns the summaries of the visitors sorted by ID, they
+// identify the visitors by their opaque ID so they can be shown without
+// revealing the IPs
+func (s *Statistics) VisitorSummaries() []VisitorSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summaries := make([]VisitorSummary, 0, len(s.Visitors))
+
+	for _, v := range s.Visitors {
+		summaries = append(summaries, v.ToDTO())
+	}
+
+	slices.SortFunc(summaries, func(a, b VisitorSummary) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return summaries
+}
+
+// HTMLOnlyVisitorsCount counts the visitors with dynamic visits that never
+// loaded a static asset. It is a heuristic bot signal, not a definitive one,
+// and it is meaningless with WithDynamicOnly
+func (s *Statistics) HTMLOnlyVisitorsCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+
+	for _, v := range s.Visitors {
+		if v.DynamicVisits > 0 && !v.LoadsAssets() {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (s *Statistics) AverageDynamicVisitsPerVisitor() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitors := len(s.Visitors)
+	if visitors == 0 {
+		return 0
+	}
+
+	totalVisits := 0
+
+	for _, v := range s.Visitors {
+		totalVisits += len(v.History)
+	}
+
+	return totalVisits / visitors
+}
+
+// GroupedVisitCounts sums the visits of the pages per group returned by
+// grouper (e.g. "Blog" for the /blog/ paths), the pages grouped under "" are
+// left out. The pages are still tracked individually
+func (s *Statistics) GroupedVisitCounts(grouper func(path string) string) map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int)
+
+	for path, page := range s.Pages {
+		if group := grouper(path); group != "" {
+			counts[group] += page.VisitsCount()
+		}
+	}
+
+	return counts
+}
+
+// VisitsByPathDepth counts the visits per number of path segments ("/" is 0,
+// "/a/b" is 2), UnmatchedPath and OtherPath are left out
+func (s *Statistics) VisitsByPathDepth() map[int]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[int]int)
+
+	for path, page := range s.Pages {
+		if path == UnmatchedPath || path == OtherPath {
+			continue
+		}
+
+		depth := 0
+
+		for _, segment := range strings.Split(path, "/") {
+			if segment != "" {
+				depth++
+			}
+		}
+
+		counts[depth] += page.VisitsCount()
+	}
+
+	return counts
+}
+
+// AverageVisitsPerPage returns the mean and the median visits count of the
+// pages, the median is less sensitive to a few very popular pages
+func (s *Statistics) AverageVisitsPerPage() (mean float64, median float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Pages) == 0 {
+		return 0, 0
+	}
+
+	counts := make([]int, 0, len(s.Pages))
+	total := 0
+
+	for _, page := range s.Pages {
+		counts = append(counts, page.VisitsCount())
+		total += page.VisitsCount()
+	}
+
+	slices.Sort(counts)
+
+	mean = float64(total) / float64(len(counts))
+	middle := len(counts) / 2
+
+	if len(counts)%2 == 0 {
+		return mean, float64(counts[middle-1] + counts[middle]) / 2
+	}
+
+	return mean, float64(counts[middle])
+}
+
+func (s *Statistics) MostVisitedPages() []*Page {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return detachPages(s.mostVisitedPages())
+}
+
+// mostVisitedPages sorts the pages by descending visits count then by path
+func (s *Statistics) mostVisitedPages() []*Page {
+	pagesSlice := make([]*Page, 0, len(s.Pages))
+
+	for _, page := range s.Pages {
+		pagesSlice = append(pagesSlice, page)
+	}
+
+	slices.SortFunc(pagesSlice, func(a, b *Page) int {
+		return cmp.Or(cmp.Compare(b.VisitsCount(), a.VisitsCount()), cmp.Compare(a.Path, b.Path))
+	})
+
+	return pagesSlice
+}
+
+// MostVisitedPagesWithShare returns the n most visited pages (all of them if
+// n <= 0) along with their share of the total visits
+func (s *Statistics) MostVisitedPagesWithShare(n int) []PageShare {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pages := s.mostVisitedPages()
+
+	total := 0
+
+	for _, page := range pages {
+		total += page.VisitsCount()
+	}
+
+	if n > 0 && n < len(pages) {
+		pages = pages[:n]
+	}
+
+	shares := make([]PageShare, 0, len(pages))
+
+	for _, page := range pages {
+		share := PageShare{
+			Page: detachPage(page),
+			Count: page.VisitsCount(),
+		}
+
+		if total > 0 {
+			share.Share = float64(share.Count) / float64(total)
+		}
+
+		shares = append(shares, share)
+	}
+
+	return shares
+}
+
+// MostActiveVisitors returns the n visitors (all of them if n <= 0) with the
+// most visits
+func (s *Statistics) MostActiveVisitors(n int) []*Visitor {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitors := make([]*Visitor, 0, len(s.Visitors))
+
+	for _, visitor := range s.Visitors {
+		visitors = append(visitors, visitor)
+	}
+
+	slices.SortFunc(visitors, func(a, b *Visitor) int {
+		return cmp.Or(cmp.Compare(b.VisitsCount(), a.VisitsCount()), cmp.Compare(a.IP, b.IP), cmp.Compare(a.Fingerprint, b.Fingerprint))
+	})
+
+	if n > 0 && n < len(visitors) {
+		visitors = visitors[:n]
+	}
+
+	return detachVisitors(visitors)
+}
+
+func (s *Statistics) LeastVisitedPages() []*Page {
+	pagesSlice := s.MostVisitedPages()
+
+	slices.Reverse(pagesSlice)
+
+	return pagesSlice
+}
+
+func (s *Statistics) LoadingTimeHistogram(buckets []time.Duration) []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visits := make([]*Visit, 0, len(s.Visits))
+
+	for _, v := range s.Visits {
+		visits = append(visits, v)
+	}
+
+	return loadingTimeHistogram(visits, buckets)
+}
+
+// MedianTimeToFirstVisit returns the median discovery time of the given pages
+// (path -> publication date), pages without visits are left out
+func (s *Statistics) MedianTimeToFirstVisit(publishedAt map[string]time.Time) time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	durations := []time.Duration{}
+
+	for path, date := range publishedAt {
+		if page, ok := s.Pages[path]; ok && len(page.Visits) > 0 {
+			durations = append(durations, page.TimeToFirstVisit(date))
+		}
+	}
+
+	return medianDuration(durations)
+}
+
+// RegressingPages returns the pages whose average loading time over the last
+// recent duration exceeds by more than threshold percent the one of the
+// baseline duration before it, the worst regression first. Both windows need
+// regressionMinSamples dynamic visits
+func (s *Statistics) RegressingPages(baseline, recent time.Duration, threshold float64) []*Page {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	split := now.Add(-recent)
+
+	type window struct {
+		total time.Duration
+		samples int
+	}
+
+	baselines := make(map[string]*window)
+	recents := make(map[string]*window)
+
+	for _, v := range s.visitsBetween(split.Add(-baseline), now) {
+		if !v.isLoadingTimeSample() {
+			continue
+		}
+
+		windows := recents
+		if v.Date.Before(split) {
+			windows = baselines
+		}
+
+		if _, ok := windows[v.Page.Path]; !ok {
+			windows[v.Page.Path] = &window{}
+		}
+
+		windows[v.Page.Path].total += v.LoadingTime
+		windows[v.Page.Path].samples++
+	}
+
+	ratios := make(map[*Page]float64)
+	pages := []*Page{}
+
+	for path, r := range recents {
+		b, ok := baselines[path]
+		if !ok || b.samples < regressionMinSamples || r.samples < regressionMinSamples || b.total == 0 {
+			continue
+		}
+
+		page, ok := s.Pages[path]
+		if !ok {
+			continue
+		}
+
+		recentAverage := float64(r.total) / float64(r.samples)
+		baselineAverage := float64(b.total) / float64(b.samples)
+
+		if recentAverage > baselineAverage * (1 + threshold / 100) {
+			ratios[page] = recentAverage / baselineAverage
+			pages = append(pages, page)
+		}
+	}
+
+	slices.SortFunc(pages, func(a, b *Page) int {
+		return cmp.Or(cmp.Compare(ratios[b], ratios[a]), cmp.Compare(a.Path, b.Path))
+	})
+
+	return detachPages(pages)
+}
+
+// PageAffinity returns the n pages (all of them if n <= 0) co-visited by the
+// most visitors of the page at path, by descending score then by path
+func (s *Statistics) PageAffinity(path string, n int) []PageScore {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pages := []PageScore{}
+
+	page, ok := s.Pages[path]
+	if !ok {
+		return pages
+	}
+
+	visitors := make(map[*Visitor]bool)
+
+	for _, v := range page.Visits {
+		visitors[v.VisitedBy] = true
+	}
+
+	counts := make(map[string]int)
+
+	for visitor := range visitors {
+		seen := make(map[*Page]bool)
+
+		for _, v := range visitor.History {
+			if v.Page != page && !seen[v.Page] {
+				seen[v.Page] = true
+				counts[v.Page.Path]++
+			}
+		}
+	}
+
+	for p, count := range counts {
+		pages = append(pages, PageScore{
+			Path: p,
+			Visitors: count,
+			Score: float64(count) / float64(len(visitors)),
+		})
+	}
+
+	slices.SortFunc(pages, func(a, b PageScore) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Path, b.Path))
+	})
+
+	if n > 0 && n < len(pages) {
+		pages = pages[:n]
+	}
+
+	return pages
+}
+
+// HottestPages returns the n pages (all of them if n <= 0) with the highest
+// TrendScore now
+func (s *Statistics) HottestPages(n int, halfLife time.Duration) []*Page {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	scores := make(map[*Page]float64, len(s.Pages))
+	pages := make([]*Page, 0, len(s.Pages))
+
+	for _, page := range s.Pages {
+		scores[page] = page.TrendScore(halfLife, now)
+		pages = append(pages, page)
+	}
+
+	slices.SortFunc(pages, func(a, b *Page) int {
+		return cmp.Or(cmp.Compare(scores[b], scores[a]), cmp.Compare(a.Path, b.Path))
+	})
+
+	if n > 0 && n < len(pages) {
+		pages = pages[:n]
+	}
+
+	return detachPages(pages)
+}
+
+// OutOfOrderCount counts the adjacent visits of the pages and of the visitors
+// histories where the second one has an earlier date, they break the binary
+// searches of GetVisit and GetVisitNearest. It is a diagnostic, the visits are
+// inserted in date order
+func (s *Statistics) OutOfOrderCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+
+	countIn := func(visits []*Visit) {
+		for i := 1; i < len(visits); i++ {
+			if visits[i].Date.Before(visits[i-1].Date) {
+				count++
+			}
+		}
+	}
+
+	for _, page := range s.Pages {
+		countIn(page.Visits)
+	}
+
+	for _, visitor := range s.Visitors {
+		countIn(visitor.History)
+	}
+
+	return count
+}
+
+// LastNVisits returns the n most recent visits newest first, all of them if
+// there are fewer. They are ordered by date, not by ID: the Middleware reserves
+// the IDs as the requests start
+func (s *Statistics) LastNVisits(n int) []*Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visits := s.detachStored(s.store.Last(n))
+
+	slices.Reverse(visits)
+
+	return visits
+}
+
+// VisitsBetween returns the visits whose date is in [start, end) sorted by date
+func (s *Statistics) VisitsBetween(start, end time.Time) []*Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.detachStored(s.visitsBetween(start, end))
+}
+
+func (s *Statistics) visitsBetween(start, end time.Time) []*Visit {
+	return s.store.Between(start, end)
+}
+
+// VisitsForPaths merges the visits of the pages in date order, unknown paths
+// are skipped
+func (s *Statistics) VisitsForPaths(paths ...string) []*Visit {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	h := visitsHeap{}
+	total := 0
+	seen := make(map[string]bool)
+
+	for _, path := range paths {
+		if page, ok := s.Pages[path]; ok && !seen[path] && len(page.Visits) > 0 {
+			h = append(h, page.Visits)
+			total += len(page.Visits)
+		}
+
+		seen[path] = true
+	}
+
+	heap.Init(&h)
+
+	visits := make([]*Visit, 0, total)
+
+	for h.Len() > 0 {
+		visits = append(visits, h[0][0])
+
+		if len(h[0]) == 1 {
+			heap.Pop(&h)
+		} else {
+			h[0] = h[0][1:]
+			heap.Fix(&h, 0)
+		}
+	}
+
+	return detachVisits(visits)
+}
+
+func (h visitsHeap) Len() int { return len(h) }
+func (h visitsHeap) Less(i, j int) bool { return h[i][0].Date.Before(h[j][0].Date) }
+func (h visitsHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *visitsHeap) Push(x any) { *h = append(*h, x.([]*Visit)) }
+
+func (h *visitsHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return last
+}
+
+func (h pagesHeap) Len() int { return len(h) }
+func (h pagesHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *pagesHeap) Push(x any) { *h = append(*h, x.(pageEntry)) }
+
+func (h pagesHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+
+	return h[i].page.Path < h[j].page.Path
+}
+
+func (h *pagesHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return last
+}
+
+// compareVisitDate never returns 0 so slices.BinarySearchFunc returns the
+// index of the first visit at or after the date
+func compareVisitDate(v *Visit, date time.Time) int {
+	if v.Date.Before(date) {
+		return -1
+	}
+
+	return 1
+}
+
+// insertByDate inserts the visit after the ones with an earlier or equal date
+// so the binary searches on dates stay correct. The search starts from the
+// end since visits mostly arrive in order, an insert is then as cheap as an
+// append and only older imported visits pay for the shift, unlike a lazy sort
+// which would cost every query following a write
+func insertByDate(visits []*Visit, visit *Visit) []*Visit {
+	i := len(visits)
+
+	for i > 0 && visits[i-1].Date.After(visit.Date) {
+		i--
+	}
+
+	return slices.Insert(visits, i, visit)
+}
+
+// indexByDate returns the index of the visit in visits sorted by date, or -1,
+// searching only the visits with the same date
+func indexByDate(visits []*Visit, visit *Visit) int {
+	i, _ := slices.BinarySearchFunc(visits, visit.Date, compareVisitDate)
+
+	for ; i < len(visits) && visits[i].Date.Equal(visit.Date); i++ {
+		if visits[i] == visit {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// mergeByDate merges two slices of visits sorted by date, the visits of a
+// coming first among equal dates
+func mergeByDate(a, b []*Visit) []*Visit {
+	merged := make([]*Visit, 0, len(a)+len(b))
+
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Date.Before(a[0].Date) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+
+	merged = append(merged, a...)
+
+	return append(merged, b...)
+}
+
+// PeakWindow returns the start of the window of the given size holding the
+// most visits and its number of visits, or zero values without visits
+func (s *Statistics) PeakWindow(window time.Duration) (time.Time, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.peakWindow(window)
+}
+
+func (s *Statistics) peakWindow(window time.Duration) (time.Time, int) {
+	var start time.Time
+	count := 0
+
+	left := 0
+	visits := s.store.All()
+
+	for right, v := range visits {
+		for left < right && v.Date.Sub(visits[left].Date) >= window {
+			left++
+		}
+
+		if right-left+1 > count {
+			start = visits[left].Date
+			count = right - left + 1
+		}
+	}
+
+	return start, count
+}
+
+// GrowthRate returns the percentage change of the metric (MetricVisits or
+// MetricVisitors) over the last period compared to the period before it.
+// It returns +Inf if the previous period is empty but not the last one, 0 if
+// both are empty and NaN for an unknown metric
+func (s *Statistics) GrowthRate(metric string, period time.Duration) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	current := s.visitsBetween(now.Add(-period), now)
+	previous := s.visitsBetween(now.Add(-2*period), now.Add(-period))
+
+	switch metric {
+	case MetricVisits:
+		return growthRate(len(current), len(previous))
+	case MetricVisitors:
+		return growthRate(uniqueVisitors(current), uniqueVisitors(previous))
+	}
+
+	return math.NaN()
+}
+
+// TrendingPages returns the n pages (all of them if n <= 0) visited during the
+// last two periods sorted by largest visits increase
+func (s *Statistics) TrendingPages(period time.Duration, n int) []PageTrend {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	current := make(map[string]int)
+	previous := make(map[string]int)
+
+	for _, v := range s.visitsBetween(now.Add(-period), now) {
+		current[v.Page.Path]++
+	}
+
+	for _, v := range s.visitsBetween(now.Add(-2*period), now.Add(-period)) {
+		previous[v.Page.Path]++
+	}
+
+	trends := []PageTrend{}
+
+	for path, page := range s.Pages {
+		if current[path] == 0 && previous[path] == 0 {
+			continue
+		}
+
+		trends = append(trends, PageTrend{
+			Page: detachPage(page),
+			Current: current[path],
+			Previous: previous[path],
+			Change: growthRate(current[path], previous[path]),
+		})
+	}
+
+	slices.SortFunc(trends, func(a, b PageTrend) int {
+		return cmp.Or(
+			cmp.Compare(b.Current-b.Previous, a.Current-a.Previous),
+			cmp.Compare(b.Change, a.Change),
+			cmp.Compare(a.Page.Path, b.Page.Path),
+		)
+	})
+
+	if n > 0 && n < len(trends) {
+		trends = trends[:n]
+	}
+
+	return trends
+}
+
+func growthRate(current, previous int) float64 {
+	if previous == 0 {
+		if current == 0 {
+			return 0
+		}
+
+		return math.Inf(1)
+	}
+
+	return float64(current-previous) / float64(previous) * 100
+}
+
+func uniqueVisitors(visits []*Visit) int {
+	visitors := make(map[string]bool)
+
+	for _, v := range visits {
+		visitors[visitorKey(v.VisitedBy)] = true
+	}
+
+	return len(visitors)
+}
+
+// RefererCounts returns the number of visits per referer host
+func (s *Statistics) RefererCounts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.refererCounts(false)
+}
+
+// CleanRefererCounts is like RefererCounts but leaves out the hosts of the
+// referer blocklist
+func (s *Statistics) CleanRefererCounts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.refererCounts(true)
+}
+
+func (s *Statistics) refererCounts(skipSpam bool) map[string]int {
+	counts := make(map[string]int)
+
+	for _, v := range s.Visits {
+		if skipSpam && v.RefererSpam {
+			continue
+		}
+
+		if host := refererHost(v.Referer); host != "" {
+			counts[host]++
+		}
+	}
+
+	return counts
+}
+
+// QueryParamCounts returns the number of visits per value of the query
+// parameter (e.g. "utm_source"), see WithQueryStrings
+func (s *Statistics) QueryParamCounts(param string) map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int)
+
+	for _, v := range s.Visits {
+		if v.Query == "" {
+			continue
+		}
+
+		values, err := url.ParseQuery(v.Query)
+		if err != nil {
+			continue
+		}
+
+		for _, value := range values[param] {
+			counts[value]++
+		}
+	}
+
+	return counts
+}
+
+// DirectTrafficShare returns the fraction of visits without a referer
+func (s *Statistics) DirectTrafficShare() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Visits) == 0 {
+		return 0
+	}
+
+	direct := 0
+
+	for _, v := range s.Visits {
+		if v.Referer == "" {
+			direct++
+		}
+	}
+
+	return float64(direct) / float64(len(s.Visits))
+}
+
+// ContentTypeTrend returns, for each media type, the number of visits per
+// time bucket in chronological order, empty buckets are omitted
+func (s *Statistics) ContentTypeTrend(bucket time.Duration) map[string][]TrendPoint {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]map[time.Time]int)
+
+	for _, v := range s.Visits {
+		category := contentTypeCategory(v.ContentType)
+
+		if _, ok := counts[category]; !ok {
+			counts[category] = make(map[time.Time]int)
+		}
+
+		counts[category][bucketStart(v.Date, bucket)]++
+	}
+
+	trend := make(map[string][]TrendPoint)
+
+	for category, buckets := range counts {
+		for start, count := range buckets {
+			trend[category] = append(trend[category], TrendPoint{
+				Start: start,
+				Count: count,
+			})
+		}
+
+		slices.SortFunc(trend[category], func(a, b TrendPoint) int {
+			return a.Start.Compare(b.Start)
+		})
+	}
+
+	return trend
+}
+
+// contentTypeCategory returns the media type of the content type without its
+// parameters, e.g. "text/html" for "text/html; charset=utf-8"
+func contentTypeCategory(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		return "unknown"
+	}
+
+	return mediaType
+}
+
+func bucketStart(date time.Time, bucket time.Duration) time.Time {
+	return date.Truncate(bucket)
+}
+
+// RetentionCohorts groups the visitors by the period they were first seen in,
+// the map is keyed by the start of each cohort period
+func (s *Statistics) RetentionCohorts(cohortPeriod time.Duration) map[time.Time]Cohort {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := bucketStart(time.Now(), cohortPeriod)
+	active := make(map[time.Time][]int)
+	sizes := make(map[time.Time]int)
+
+	for _, v := range s.Visitors {
+		if v.FirstSeen.IsZero() {
+			continue
+		}
+
+		cohort := bucketStart(v.FirstSeen, cohortPeriod)
+
+		if _, ok := active[cohort]; !ok {
+			active[cohort] = make([]int, int(now.Sub(cohort)/cohortPeriod)+1)
+		}
+
+		sizes[cohort]++
+
+		periods := make(map[int]bool)
+
+		for _, vi := range v.History {
+			periods[int(bucketStart(vi.Date, cohortPeriod).Sub(cohort)/cohortPeriod)] = true
+		}
+
+		for period := range periods {
+			if period >= 0 && period < len(active[cohort]) {
+				active[cohort][period]++
+			}
+		}
+	}
+
+	cohorts := make(map[time.Time]Cohort)
+
+	for start, counts := range active {
+		cohort := Cohort{
+			Size: sizes[start],
+			Retention: make([]float64, len(counts)),
+		}
+
+		for period, count := range counts {
+			cohort.Retention[period] = float64(count) / float64(cohort.Size)
+		}
+
+		cohorts[start] = cohort
+	}
+
+	return cohorts
+}
+
+func (s *Statistics) TotalRequestSize() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.totalRequestSize()
+}
+
+func (s *Statistics) totalRequestSize() int64 {
+	total := int64(0)
+
+	for _, v := range s.Visits {
+		total += v.RequestSize
+	}
+
+	return total
+}
+
+func (s *Statistics) AverageRequestSize() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Visits) == 0 {
+		return 0
+	}
+
+	return s.totalRequestSize() / int64(len(s.Visits))
+}
+
+// ClientClassCounts returns the number of visits per client class
+func (s *Statistics) ClientClassCounts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int)
+
+	for _, v := range s.Visits {
+		counts[v.ClientClass]++
+	}
+
+	return counts
+}
+
+func (s *Statistics) VisitsByType() map[PageType]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[PageType]int)
+
+	for _, v := range s.Visits {
+		counts[v.Type]++
+	}
+
+	return counts
+}
+
+// ContentLanguageCounts returns the number of visits per Content-Language
+// served, visits without one are left out
+func (s *Statistics) ContentLanguageCounts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int)
+
+	for _, v := range s.Visits {
+		if v.ContentLanguage != "" {
+			counts[v.ContentLanguage]++
+		}
+	}
+
+	return counts
+}
+
+// ProtocolCounts returns the number of visits per request protocol, e.g.
+// "HTTP/1.1" or "HTTP/2.0"
+func (s *Statistics) ProtocolCounts() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]int)
+
+	for _, v := range s.Visits {
+		if v.Protocol != "" {
+			counts[v.Protocol]++
+		}
+	}
+
+	return counts
+}
+
+// CompressedShare returns the fraction of visits whose response had a
+// Content-Encoding
+func (s *Statistics) CompressedShare() float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.Visits) == 0 {
+		return 0
+	}
+
+	compressed := 0
+
+	for _, v := range s.Visits {
+		if v.Compressed {
+			compressed++
+		}
+	}
+
+	return float64(compressed) / float64(len(s.Visits))
+}
+
+func (s *Statistics) LanguagesCount() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return maps.Clone(s.VisitorsLanguage)
+}
+
+// VisitsCount includes the PriorVisits imported with ImportAggregates
+func (p *Page) VisitsCount() int {
+	return len(p.Visits) + p.PriorVisits
+}
+
+func (p *Page) TotalRequestSize() int64 {
+	total := int64(0)
+
+	for _, v := range p.Visits {
+		total += v.RequestSize
+	}
+
+	return total
+}
+
+func (p *Page) AverageRequestSize() int64 {
+	if len(p.Visits) == 0 {
+		return 0
+	}
+
+	return p.TotalRequestSize() / int64(len(p.Visits))
+}
+
+func (p *Page) VisitsByType() map[PageType]int {
+	return visitsByType(p.Visits)
+}
+
+func visitsByType(visits []*Visit) map[PageType]int {
+	counts := make(map[PageType]int)
+
+	for _, v := range visits {
+		counts[v.Type]++
+	}
+
+	return counts
+}
+
+func (p *Page) VisitorsCount() int {
+	return uniqueVisitors(p.Visits)
+}
+
+// MedianLoadingTime returns the median loading time of the dynamic visits of
+// the page, warmup visits excepted
+func (p *Page) MedianLoadingTime() time.Duration {
+	return percentile(sortedLoadingTimes(p.Visits), 50)
+}
+
+// MedianTimeSpent returns the median time spent on the page by its dynamic
+// visits
+func (p *Page) MedianTimeSpent() time.Duration {
+	samples := []time.Duration{}
+
+	for _, v := range p.Visits {
+		if v.Type == Dynamic {
+			samples = append(samples, v.TimeSpent)
+		}
+	}
+
+	slices.Sort(samples)
+
+	return percentile(samples, 50)
+}
+
+// AverageInterVisitTime returns the mean gap between consecutive visits of the
+// page, or 0 with fewer than two visits
+func (p *Page) AverageInterVisitTime() time.Duration {
+	if len(p.Visits) < 2 {
+		return 0
+	}
+
+	// the visits are sorted by date so the gaps add up to the whole span
+	return p.Visits[len(p.Visits)-1].Date.Sub(p.Visits[0].Date) / time.Duration(len(p.Visits)-1)
+}
+
+// TrendScore sums the visits weighted by 0.5^(age/halfLife) so the recent ones
+// dominate, PriorVisits have no date and don't count. It is 0 if halfLife <= 0
+func (p *Page) TrendScore(halfLife time.Duration, now time.Time) float64 {
+	if halfLife <= 0 {
+		return 0
+	}
+
+	score := 0.0
+
+	for _, v := range p.Visits {
+		age := max(now.Sub(v.Date), 0)
+		score += math.Exp2(-float64(age) / float64(halfLife))
+	}
+
+	return score
+}
+
+// NewVisitorShare returns the fraction of the visits of the page that were the
+// first visit of their visitor to the site, high for acquisition pages
+func (p *Page) NewVisitorShare() float64 {
+	if len(p.Visits) == 0 {
+		return 0
+	}
+
+	first := 0
+
+	for _, v := range p.Visits {
+		if v.VisitedBy != nil && v.Date.Equal(v.VisitedBy.FirstSeen) {
+			first++
+		}
+	}
+
+	return float64(first) / float64(len(p.Visits))
+}
+
+// UniqueRefererCount counts the distinct referer hosts linking to the page
+func (p *Page) UniqueRefererCount() int {
+	hosts := make(map[string]bool)
+
+	for _, v := range p.Visits {
+		if host := refererHost(v.Referer); host != "" {
+			hosts[host] = true
+		}
+	}
+
+	return len(hosts)
+}
+
+func (p *Page) AverageTimeSpent() time.Duration {
+	i := 0
+	totalTimeSpent := time.Duration(0)
+
+	for _, v := range p.Visits {
+		if v.Type == Dynamic {
+			i++
+			totalTimeSpent += v.TimeSpent
+		}
+	}
+
+	if i == 0 {
+		return 0
+	}
+
+	return totalTimeSpent / time.Duration(i)
+}
+
+func (p *Page) AverageLoadingTime() time.Duration {
+	i := 0
+	totalLoadingTime := time.Duration(0)
+
+	for _, v := range p.Visits {
+		if v.isLoadingTimeSample() {
+			i++
+			totalLoadingTime += v.LoadingTime
+		}
+	}
+
+	if i == 0 {
+		return 0
+	}
+
+	return totalLoadingTime / time.Duration(i)
+}
+
+// LoadingTimeByType returns the average loading time of the page per visit
+// type, warmup visits excepted
+func (p *Page) LoadingTimeByType() map[PageType]time.Duration {
+	totals := make(map[PageType]time.Duration)
+	counts := make(map[PageType]int)
+
+	for _, v := range p.Visits {
+		if !v.Warmup {
+			totals[v.Type] += v.LoadingTime
+			counts[v.Type]++
+		}
+	}
+
+	averages := make(map[PageType]time.Duration, len(counts))
+
+	for pageType, count := range counts {
+		averages[pageType] = totals[pageType] / time.Duration(count)
+	}
+
+	return averages
+}
+
+// TrimmedAverageLoadingTime returns the mean loading time of the dynamic visits
+// once the trimFraction fastest and slowest ones are discarded. It returns 0
+// for a page without dynamic visits or a trimFraction outside of [0, 0.5)
+func (p *Page) TrimmedAverageLoadingTime(trimFraction float64) time.Duration {
+	if trimFraction < 0 || trimFraction >= 0.5 {
+		return 0
+	}
+
+	samples := sortedLoadingTimes(p.Visits)
+
+	trim := int(float64(len(samples)) * trimFraction)
+	samples = samples[trim:len(samples)-trim]
+
+	if len(samples) == 0 {
+		return 0
+	}
+
+	total := time.Duration(0)
+
+	for _, sample := range samples {
+		total += sample
+	}
+
+	return total / time.Duration(len(samples))
+}
+
+// Equal reports whether both visits recorded the same request: the ID and
+// TimeSpent, which depend on the instance and on the next visits, are ignored
+// and the page and the visitor are compared by path and by IP and fingerprint
+func (v *Visit) Equal(other *Visit) bool {
+	if v == nil || other == nil {
+		return v == other
+	}
+
+	samePage := (v.Page == nil) == (other.Page == nil) && (v.Page == nil || v.Page.Path == other.Page.Path)
+	sameVisitor := (v.VisitedBy == nil) == (other.VisitedBy == nil) && (v.VisitedBy == nil || (v.VisitedBy.IP == other.VisitedBy.IP && v.VisitedBy.Fingerprint == other.VisitedBy.Fingerprint))
+
+	return samePage && sameVisitor &&
+		v.Date.Equal(other.Date) &&
+		v.Type == other.Type &&
+		v.LoadingTime == other.LoadingTime &&
+		v.CodeIssued == other.CodeIssued &&
+		v.ContentType == other.ContentType &&
+		v.ConcretePath == other.ConcretePath &&
+		v.Query == other.Query &&
+		v.Referer == other.Referer &&
+		v.RefererSpam == other.RefererSpam &&
+		v.UserID == other.UserID &&
+		v.RequestSize == other.RequestSize &&
+		v.ResponseSize == other.ResponseSize &&
+		v.UserAgent == other.UserAgent &&
+		v.ClientClass == other.ClientClass &&
+		v.Language == other.Language &&
+		v.ContentLanguage == other.ContentLanguage &&
+		v.Compressed == other.Compressed &&
+		v.Converted == other.Converted &&
+		v.Protocol == other.Protocol &&
+		v.TLS == other.TLS &&
+		v.Warmup == other.Warmup
+}
+
+// SortVisits sorts the visits by date then by ID
+func SortVisits(visits []*Visit) {
+	slices.SortStableFunc(visits, func(a, b *Visit) int {
+		return cmp.Or(a.Date.Compare(b.Date), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// isLoadingTimeSample reports whether the visit counts in the loading time
+// metrics, which only cover the dynamic visits made after the warmup period
+func (v *Visit) isLoadingTimeSample() bool {
+	return v.Type == Dynamic && !v.Warmup
+}
+
+// sortedLoadingTimes returns the loading times of the dynamic visits in
+// ascending order
+func sortedLoadingTimes(visits []*Visit) []time.Duration {
+	samples := []time.Duration{}
+
+	for _, v := range visits {
+		if v.isLoadingTimeSample() {
+			samples = append(samples, v.LoadingTime)
+		}
+	}
+
+	slices.Sort(samples)
+
+	return samples
+}
+
+// percentile returns the nearest-rank p-th percentile (0-100) of the sorted
+// samples, or 0 without samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+
+	return sorted[min(max(rank-1, 0), len(sorted)-1)]
+}
+
+func (p *Page) LoadingTimeHistogram(buckets []time.Duration) []int {
+	return loadingTimeHistogram(p.Visits, buckets)
+}
+
+// loadingTimeHistogram counts the dynamic visits whose loading time is lower
+// or equal to each of the ascending bucket boundaries, the counts are
+// cumulative like prometheus buckets and the last one is the +Inf bucket
+func loadingTimeHistogram(visits []*Visit, buckets []time.Duration) []int {
+	counts := make([]int, len(buckets)+1)
+
+	for _, v := range visits {
+		if !v.isLoadingTimeSample() {
+			continue
+		}
+
+		for i, bucket := range buckets {
+			if v.LoadingTime <= bucket {
+				counts[i]++
+			}
+		}
+
+		counts[len(buckets)]++
+	}
+
+	return counts
+}
+
+// TimeToFirstVisit returns the time between the publication of the page and
+// its first visit, or 0 if the page has never been visited
+func (p *Page) TimeToFirstVisit(publishedAt time.Time) time.Duration {
+	if len(p.Visits) == 0 {
+		return 0
+	}
+
+	return p.FirstSeen.Sub(publishedAt)
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
+
+func (p *Page) GetVisit(date time.Time) (*Visit, error) {
+	index, found := slices.BinarySearchFunc(p.Visits, &Visit{Date: date}, func(a, b *Visit) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	if found {
+		return p.Visits[index], nil
+	}
+
+	return nil, fmt.Errorf("visit not found")
+}
+
+func (p *Page) GetVisitNearest(date time.Time) *Visit {
+	return nearestVisit(p.Visits, date)
+}
+
+// nearestVisit returns the visit whose date is the closest to the given one,
+// or nil if there are no visits
+func nearestVisit(visits []*Visit, date time.Time) *Visit {
+	if len(visits) == 0 {
+		return nil
+	}
+
+	index, found := slices.BinarySearchFunc(visits, &Visit{Date: date}, func(a, b *Visit) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	if found || index == 0 {
+		return visits[index]
+	}
+
+	if index == len(visits) {
+		return visits[index-1]
+	}
+
+	before, after := visits[index-1], visits[index]
+
+	if date.Sub(before.Date) <= after.Date.Sub(date) {
+		return before
+	}
+
+	return after
+}
+
+//func (v *Visitor) PrettyHistory() string {
+//	history := ""
+//
+//	for p := range v.History {
+//
+//	}
+//}
+
+func (v *Visitor) VisitsCount() int {
+	return len(v.History)
+}
+
+func (v *Visitor) VisitsByType() map[PageType]int {
+	return visitsByType(v.History)
+}
+
+// LoadsAssets reports whether the visitor loaded any static asset, browsers do
+// while bots and API clients usually don't so it's a heuristic only
+func (v *Visitor) LoadsAssets() bool {
+	return v.StaticVisits > 0
+}
+
+// Languages returns the language tags declared by the Accept-Language of the
+// first visit of the visitor, see declaredLanguages
+func (v *Visitor) Languages() []string {
+	return declaredLanguages(v.Language)
+}
+
+// TopPages returns the n pages (all of them if n <= 0) the visitor visited the
+// most, by descending visits then by path
+func (v *Visitor) TopPages(n int) []PageCount {
+	counts := make(map[string]int)
+
+	for _, vi := range v.History {
+		counts[vi.Page.Path]++
+	}
+
+	pages := make([]PageCount, 0, len(counts))
+
+	for path, count := range counts {
+		pages = append(pages, PageCount{
+			Path: path,
+			Count: count,
+		})
+	}
+
+	sortPageCounts(pages)
+
+	if n > 0 && n < len(pages) {
+		pages = pages[:n]
+	}
+
+	return pages
+}
+
+// FavoritePage returns the first page of TopPages, or nil without history
+func (v *Visitor) FavoritePage() *Page {
+	top := v.TopPages(1)
+	if len(top) == 0 {
+		return nil
+	}
+
+	for _, vi := range v.History {
+		if vi.Page.Path == top[0].Path {
+			return vi.Page
+		}
+	}
+
+	return nil
+}
+
+// LanguageChanged reports whether a visit sent another Accept-Language than
+// the first one of the visitor, a hint of a shared NAT or of a bot rotating
+// its headers
+func (v *Visitor) LanguageChanged() bool {
+	for _, vi := range v.History {
+		if vi.Language != v.Language {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotFoundRate returns the fraction of the visits that issued a 404
+func (v *Visitor) NotFoundRate() float64 {
+	if len(v.History) == 0 {
+		return 0
+	}
+
+	notFound := 0
+
+	for _, vi := range v.History {
+		if vi.CodeIssued == http.StatusNotFound {
+			notFound++
+		}
+	}
+
+	return float64(notFound) / float64(len(v.History))
+}
+
+func (v *Visitor) AverageTimeSpent() time.Duration {
+	i := 0
+	totalTimeSpent := time.Duration(0)
+
+	for _, vi := range v.History {
+		if vi.Type == Dynamic {
+			i++
+			totalTimeSpent += vi.TimeSpent
+		}
+	}
+
+	if i == 0 {
+		return 0
+	}
+
+	return totalTimeSpent / time.Duration(i)
+}
+
+func (v *Visitor) LastVisit() *Visit {
+	return v.History[len(v.History)-1]
+}
+
+func (v *Visitor) LastDynamicVisit() *Visit {
+	if v.lastDynamicVisit != nil {
+		return v.lastDynamicVisit
+	}
+
+	return &Visit{}
+}
+
+func (v *Visitor) GetVisit(date time.Time) (*Visit, error) {
+	index, found := slices.BinarySearchFunc(v.History, &Visit{Date: date}, func(a, b *Visit) int {
+		return a.Date.Compare(b.Date)
+	})
+
+	if found {
+		return v.History[index], nil
+	}
+
+	return nil, fmt.Errorf("visit not found")
+}
+
+func (v *Visitor) GetVisitNearest(date time.Time) *Visit {
+	return nearestVisit(v.History, date)
+}
\ No newline at end of file
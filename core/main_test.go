@@ -0,0 +1,765 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordPage records a 200 html visit of path by ip
+func recordPage(s *Statistics, ip, path string) *Visit {
+	visit, _ := s.Record(context.Background(), VisitInput{
+		IP: ip,
+		Path: path,
+		CodeIssued: http.StatusOK,
+		ContentType: "text/html; charset=utf-8",
+	})
+
+	return visit
+}
+
+// benchmarkScrapedUnderLoad measures read while the visits are recorded in
+// the background, as when /stats is scraped by a busy app
+func benchmarkScrapedUnderLoad(b *testing.B, read func(s *Statistics) int) {
+	s := New()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				recordPage(s, fmt.Sprintf("10.0.%d.%d", i/256%256, i%256), fmt.Sprintf("/page/%d", i%100))
+			}
+		}
+	}()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			read(s)
+		}
+	})
+
+	b.StopTimer()
+	close(done)
+	<-stopped
+}
+
+func BenchmarkVisitsCount(b *testing.B) {
+	benchmarkScrapedUnderLoad(b, func(s *Statistics) int {
+		return s.VisitsCount() + s.VisitorsCount()
+	})
+}
+
+// BenchmarkVisitsCountLocked reads the counts as before the atomic counters,
+// the map lengths under the mutex
+func BenchmarkVisitsCountLocked(b *testing.B) {
+	benchmarkScrapedUnderLoad(b, func(s *Statistics) int {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		return len(s.Visits) + len(s.Visitors)
+	})
+}
+
+func TestMaxPagesEvictsLeastVisited(t *testing.T) {
+	s := New(WithMaxPages(3))
+
+	for i, path := range []string{"/a", "/a", "/a", "/b", "/b", "/c", "/d", "/b", "/e"} {
+		recordPage(s, fmt.Sprintf("10.0.0.%d", i), path)
+	}
+
+	// /c, the least visited page, is evicted for /d, then /d for /e
+	for path, want := range map[string]int{"/a": 3, "/b": 3, "/e": 1, OtherPath: 2} {
+		if got := s.GetPage(path).VisitsCount(); got != want {
+			t.Errorf("%s has %d visits, want %d", path, got, want)
+		}
+	}
+
+	if s.HasPage("/c") || s.HasPage("/d") {
+		t.Errorf("/c and /d weren't evicted")
+	}
+
+	other := s.GetPage(OtherPath).Visits
+	if other[1].Date.Before(other[0].Date) {
+		t.Errorf("the %s visits aren't sorted by date", OtherPath)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormalizeIP(t *testing.T) {
+	for ip, want := range map[string]string{
+		"1.2.3.4:5678": "1.2.3.4",
+		"[::1]:80": "::1",
+		"fe80::1%eth0": "fe80::1",
+		"[fe80::1%eth0]:443": "fe80::1",
+		"::ffff:1.2.3.4": "1.2.3.4",
+		"1.2.3.4": "1.2.3.4",
+		"session-cookie": "session-cookie",
+	} {
+		if got := normalizeIP(ip); got != want {
+			t.Errorf("normalizeIP(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestVisitorKeysAreNormalized(t *testing.T) {
+	s := New()
+
+	recordPage(s, "1.2.3.4:5678", "/")
+	recordPage(s, "::ffff:1.2.3.4", "/")
+	recordPage(s, "fe80::1%eth0", "/")
+	recordPage(s, "[fe80::1%eth1]:80", "/")
+
+	if got := s.VisitorsCount(); got != 2 {
+		t.Errorf("VisitorsCount() = %d, want 2", got)
+	}
+
+	for _, ip := range []string{"1.2.3.4", "[::ffff:1.2.3.4]:80", "fe80::1%eth0", "fe80::1"} {
+		if got := len(s.GetVisitor(ip).History); got != 2 {
+			t.Errorf("GetVisitor(%q) has %d visits, want 2", ip, got)
+		}
+
+		if got := len(s.VisitorJourney(ip)); got != 2 {
+			t.Errorf("VisitorJourney(%q) has %d steps, want 2", ip, got)
+		}
+
+		if got := s.VisitorSessionCount(ip, time.Hour); got != 1 {
+			t.Errorf("VisitorSessionCount(%q) = %d, want 1", ip, got)
+		}
+	}
+
+	if err := s.MergeVisitors("1.2.3.4:80", "fe80::1%eth0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(s.GetVisitor("1.2.3.4").History); got != 4 {
+		t.Errorf("merged visitor has %d visits, want 4", got)
+	}
+}
+
+// longHistoryVisitor returns a visitor whose dynamic visit is followed by n
+// static ones, the worst case of a backward scan
+func longHistoryVisitor(n int) *Visitor {
+	s := New()
+	recordPage(s, "10.0.0.1", "/")
+
+	for i := 0; i < n; i++ {
+		s.Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/app.js", CodeIssued: http.StatusOK})
+	}
+
+	return s.Visitors["10.0.0.1"]
+}
+
+// scanLastDynamicVisit is LastDynamicVisit as it was before the
+// lastDynamicVisit pointer
+func scanLastDynamicVisit(v *Visitor) *Visit {
+	for i := len(v.History) - 1; i >= 0; i-- {
+		if v.History[i].Type == Dynamic {
+			return v.History[i]
+		}
+	}
+
+	return &Visit{}
+}
+
+func BenchmarkLastDynamicVisit(b *testing.B) {
+	visitor := longHistoryVisitor(10000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		visitor.LastDynamicVisit()
+	}
+}
+
+func BenchmarkLastDynamicVisitScan(b *testing.B) {
+	visitor := longHistoryVisitor(10000)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanLastDynamicVisit(visitor)
+	}
+}
+
+func TestRefererHostOnly(t *testing.T) {
+	for referer, want := range map[string]string{
+		"https://x.com/secret?t=abc": "https://x.com",
+		"http://x.com:8080/a#b": "http://x.com:8080",
+		"": "",
+		"not a url": "",
+	} {
+		if got := refererOrigin(referer); got != want {
+			t.Errorf("refererOrigin(%q) = %q, want %q", referer, got, want)
+		}
+	}
+
+	s := New(WithRefererHostOnly())
+	visit, _ := s.Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/", CodeIssued: http.StatusOK, Referer: "https://x.com/secret?t=abc"})
+
+	if visit.Referer != "https://x.com" {
+		t.Errorf("Referer = %q, want %q", visit.Referer, "https://x.com")
+	}
+
+	visit, _ = New().Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/", CodeIssued: http.StatusOK, Referer: "https://x.com/secret?t=abc"})
+
+	if visit.Referer != "https://x.com/secret?t=abc" {
+		t.Errorf("Referer = %q without WithRefererHostOnly, want the full referer", visit.Referer)
+	}
+}
+
+// TestNoPageTypeLiterals fails if a page type is written as a string literal
+// instead of its constant, which would break if the constant changed
+func TestNoPageTypeLiterals(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ast.Inspect(file, func(node ast.Node) bool {
+			// the declaration of the constants is the only expected literal
+			if spec, ok := node.(*ast.ValueSpec); ok && len(spec.Names) == 1 && (spec.Names[0].Name == "Dynamic" || spec.Names[0].Name == "Static") {
+				return false
+			}
+
+			lit, ok := node.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+
+			if value, _ := strconv.Unquote(lit.Value); value == string(Dynamic) || value == string(Static) {
+				t.Errorf("%s: %s literal instead of the PageType constant", fset.Position(lit.Pos()), lit.Value)
+			}
+
+			return true
+		})
+	}
+}
+
+func TestTimeSpentCountsDynamicVisits(t *testing.T) {
+	s := New()
+	start := time.Now().Add(-time.Hour)
+
+	s.Import([]VisitInput{
+		{Date: start, IP: "10.0.0.1", Path: "/", Type: Dynamic},
+		{Date: start.Add(time.Minute), IP: "10.0.0.1", Path: "/style.css", Type: Static},
+		{Date: start.Add(2 * time.Minute), IP: "10.0.0.1", Path: "/about", Type: Dynamic},
+	})
+
+	if got := s.GetPage("/").AverageTimeSpent(); got != 2*time.Minute {
+		t.Errorf("Page.AverageTimeSpent() = %v, want 2m", got)
+	}
+
+	// the last dynamic visit has no time spent yet
+	if got := s.GetVisitor("10.0.0.1").AverageTimeSpent(); got != time.Minute {
+		t.Errorf("Visitor.AverageTimeSpent() = %v, want 1m", got)
+	}
+}
+
+func TestMaxHistoryPerVisitor(t *testing.T) {
+	s := New(WithMaxHistoryPerVisitor(2))
+	start := time.Now().Add(-time.Hour)
+	inputs := []VisitInput{}
+
+	// equal dates make the removal look past the first visit of the date
+	for i, path := range []string{"/a", "/b", "/c", "/d"} {
+		inputs = append(inputs, VisitInput{Date: start.Add(time.Duration(i/2) * time.Minute), IP: "10.0.0.1", Path: path})
+		inputs = append(inputs, VisitInput{Date: start.Add(time.Duration(i/2) * time.Minute), IP: "10.0.0.2", Path: path})
+	}
+
+	if err := s.Import(inputs); err != nil {
+		t.Fatal(err)
+	}
+
+	visitor := s.GetVisitor("10.0.0.1")
+
+	if len(visitor.History) != 2 || visitor.History[0].Page.Path != "/c" || visitor.History[1].Page.Path != "/d" {
+		t.Errorf("the history isn't the last 2 visits: %v", visitor.History)
+	}
+
+	if visitor.DynamicVisits != 4 {
+		t.Errorf("DynamicVisits = %d, want 4", visitor.DynamicVisits)
+	}
+
+	if got := s.VisitsCount(); got != 8 {
+		t.Errorf("VisitsCount() = %d, want 8", got)
+	}
+
+	if got := len(s.VisitsBetween(start, time.Now())); got != 4 {
+		t.Errorf("VisitsBetween returned %d visits, want 4", got)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMaxHistoryPerVisitorTrimsBackdatedVisit(t *testing.T) {
+	s := New(WithMaxHistoryPerVisitor(1))
+	now := time.Now()
+
+	// one Import at a time so the backdated visit is recorded last
+	for _, date := range []time.Time{now, now.Add(-time.Hour)} {
+		if err := s.Import([]VisitInput{{Date: date, IP: "10.0.0.1", Path: "/", UserID: "u"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if visits := s.VisitsByUser("u"); len(visits) != 1 || !visits[0].Date.Equal(now) {
+		t.Errorf("VisitsByUser returned %d visits, want the one at now", len(visits))
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkRecordAtMaxHistory(b *testing.B) {
+	s := New(WithMaxHistoryPerVisitor(100))
+
+	for i := 0; i < 100000; i++ {
+		recordPage(s, fmt.Sprintf("10.0.%d.%d", i/256%256, i%256), "/")
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		recordPage(s, "10.1.0.1", "/")
+	}
+}
+
+func TestPageTypeByExtension(t *testing.T) {
+	s := New()
+
+	if got := s.pageType("/app.js", "application/octet-stream"); got != Static {
+		t.Errorf("a .js file served as application/octet-stream is %q, want Static", got)
+	}
+
+	if got := s.pageType("/APP.JS", ""); got != Static {
+		t.Errorf("a .JS file without Content-Type is %q, want Static", got)
+	}
+
+	if got := s.pageType("/user/42", "text/html; charset=utf-8"); got != Dynamic {
+		t.Errorf("an html page is %q, want Dynamic", got)
+	}
+
+	s = New(WithoutStaticExtensions(".XML"), WithStaticExtensions(".bin"))
+
+	if got := s.pageType("/sitemap.xml", "text/html"); got != Dynamic {
+		t.Errorf("a dynamic /sitemap.xml without the .xml extension is %q, want Dynamic", got)
+	}
+
+	if got := s.pageType("/data.bin", "text/html"); got != Static {
+		t.Errorf("a .bin file is %q, want Static", got)
+	}
+
+	s = New(WithoutStaticExtensions(), WithStaticExtensions(".css"))
+
+	if got := s.pageType("/app.js", "text/html"); got != Dynamic {
+		t.Errorf("a .js file without the default extensions is %q, want Dynamic", got)
+	}
+
+	if got := s.pageType("/style.css", "text/html"); got != Static {
+		t.Errorf("a .css file is %q, want Static", got)
+	}
+}
+
+func TestPrimaryLanguagesSumToVisitors(t *testing.T) {
+	s := New(WithPrimaryLanguageOnly())
+
+	for i, header := range []string{"fr-CH, fr;q=0.9, en;q=0.8", "en-US,en;q=0.9", "en", "de;q=0.5, en", "", "*"} {
+		s.Record(context.Background(), VisitInput{IP: fmt.Sprintf("10.0.0.%d", i), Path: "/", CodeIssued: http.StatusOK, AcceptLanguage: header})
+	}
+
+	// a returning visitor isn't counted again
+	s.Record(context.Background(), VisitInput{IP: "10.0.0.0", Path: "/", CodeIssued: http.StatusOK, AcceptLanguage: "de"})
+
+	sum := 0
+	for _, count := range s.LanguagesCount() {
+		sum += count
+	}
+
+	if sum != s.VisitorsCount() {
+		t.Errorf("the primary languages sum to %d, want VisitorsCount() = %d", sum, s.VisitorsCount())
+	}
+
+	for language, want := range map[string]int{"fr": 1, "en": 2, "de": 1, UnknownLanguage: 2} {
+		if got := s.LanguagesCount()[language]; got != want {
+			t.Errorf("%s is counted %d times, want %d", language, got, want)
+		}
+	}
+}
+
+// TestConcurrentReadsDuringWrites reads the values returned by the Statistics
+// methods while visits are recorded, run with -race
+func TestConcurrentReadsDuringWrites(t *testing.T) {
+	s := New(WithMaxPages(20), WithMaxHistoryPerVisitor(50), WithOnVisit(func(v *Visit) {
+		_ = v.TimeSpent + v.LoadingTime
+		_ = v.Page.Path + v.VisitedBy.IP
+	}))
+	s.DefineSegment("all", func(*Visitor) bool { return true })
+
+	done := make(chan struct{})
+	writers := make(chan struct{})
+
+	for w := 0; w < 4; w++ {
+		go func() {
+			defer func() { writers <- struct{}{} }()
+
+			for i := 0; i < 500; i++ {
+				s.Record(context.Background(), VisitInput{
+					IP: fmt.Sprintf("10.0.0.%d", i%8),
+					Path: fmt.Sprintf("/page/%d", (i*w)%30),
+					CodeIssued: http.StatusOK,
+					ContentType: "text/html",
+					AcceptLanguage: "fr-CH, fr;q=0.9, en;q=0.8",
+				})
+			}
+		}()
+	}
+
+	go func() {
+		for w := 0; w < 4; w++ {
+			<-writers
+		}
+
+		close(done)
+	}()
+
+	readPages := func(pages []*Page) {
+		for _, page := range pages {
+			_ = page.VisitsCount() + int(page.AverageTimeSpent())
+
+			for _, v := range page.Visits {
+				_ = v.VisitedBy.IP
+			}
+		}
+	}
+
+	readVisitors := func(visitors []*Visitor) {
+		for _, visitor := range visitors {
+			_ = visitor.VisitsCount() + int(visitor.AverageTimeSpent()) + len(visitor.LastDynamicVisit().ConcretePath)
+		}
+	}
+
+	readVisits := func(visits []*Visit) {
+		for _, v := range visits {
+			_ = v.TimeSpent + v.LoadingTime
+			_ = v.Page.Path + v.VisitedBy.IP
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		readPages(s.MostVisitedPages())
+		readPages(s.HottestPages(5, time.Minute))
+		readVisitors(s.MostActiveVisitors(5))
+		readVisitors(s.Segment("all"))
+		readVisitors(s.LikelyScanners(0))
+		readVisitors([]*Visitor{s.GetVisitor("10.0.0.1")})
+		readVisits(s.VisitsBetween(time.Now().Add(-time.Minute), time.Now()))
+		readVisits(s.LastNVisits(10))
+		readVisits(s.FilterVisits(ByType(Dynamic)))
+		readVisits(s.VisitsForPaths("/page/0", "/page/1"))
+
+		for _, share := range s.MostVisitedPagesWithShare(3) {
+			readPages([]*Page{share.Page})
+		}
+
+		for _, trend := range s.TrendingPages(time.Minute, 3) {
+			readPages([]*Page{trend.Page})
+		}
+
+		for language, count := range s.LanguagesCount() {
+			_ = len(language) + count
+		}
+
+		_ = s.AverageDynamicVisitsPerVisitor()
+	}
+}
+
+func TestAverageDynamicVisitsPerVisitorWithoutVisitors(t *testing.T) {
+	if got := New().AverageDynamicVisitsPerVisitor(); got != 0 {
+		t.Errorf("AverageDynamicVisitsPerVisitor() = %d without visitors, want 0", got)
+	}
+}
+
+func TestSuccessfulOnly(t *testing.T) {
+	s := New(WithSuccessfulOnly())
+
+	for _, code := range []int{http.StatusInternalServerError, http.StatusNotFound, StatusHijacked} {
+		if _, ok := s.Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/", CodeIssued: code}); ok {
+			t.Errorf("a %d response was recorded", code)
+		}
+	}
+
+	for _, code := range []int{http.StatusOK, http.StatusNotModified} {
+		if _, ok := s.Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/", CodeIssued: code}); !ok {
+			t.Errorf("a %d response wasn't recorded", code)
+		}
+	}
+
+	if got := s.VisitsCount(); got != 2 {
+		t.Errorf("VisitsCount() = %d, want 2", got)
+	}
+}
+
+// TestConcurrentVisitIDs records visits with reserved IDs from several
+// goroutines, run with -race
+func TestConcurrentVisitIDs(t *testing.T) {
+	s := New()
+	ids := make(chan int, 800)
+	done := make(chan struct{})
+
+	for w := 0; w < 8; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			for i := 0; i < 100; i++ {
+				input := VisitInput{ID: s.ReserveVisitID(), IP: fmt.Sprintf("10.0.%d.%d", w, i), Path: "/", CodeIssued: http.StatusOK}
+
+				visit, _ := s.Record(context.Background(), input)
+				ids <- visit.ID
+			}
+		}()
+	}
+
+	for w := 0; w < 8; w++ {
+		<-done
+	}
+
+	close(ids)
+	seen := make(map[int]bool)
+
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("the visit ID %d was allocated twice", id)
+		}
+
+		seen[id] = true
+	}
+
+	if len(seen) != 800 || s.VisitsCount() != 800 {
+		t.Errorf("recorded %d visits with %d IDs, want 800", s.VisitsCount(), len(seen))
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// BenchmarkRecordParallel measures the throughput of concurrent requests, the
+// ID reserved before the handlers then the visit recorded after them
+func BenchmarkRecordParallel(b *testing.B) {
+	s := New()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			s.Record(context.Background(), VisitInput{
+				ID: s.ReserveVisitID(),
+				IP: fmt.Sprintf("10.0.0.%d", i%256),
+				Path: "/",
+				CodeIssued: http.StatusOK,
+				ContentType: "text/html",
+			})
+		}
+	})
+}
+
+func BenchmarkReserveVisitID(b *testing.B) {
+	s := New()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.ReserveVisitID()
+		}
+	})
+}
+
+// BenchmarkReserveVisitIDLocked reserves the IDs as before the atomic counter,
+// under the mutex
+func BenchmarkReserveVisitIDLocked(b *testing.B) {
+	s := New()
+	id := 0
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.mutex.Lock()
+			id++
+			s.mutex.Unlock()
+		}
+	})
+}
+
+// insertBenchmarkVisits returns n visits one second apart
+func insertBenchmarkVisits(n int) []*Visit {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	visits := make([]*Visit, 0, n)
+
+	for i := 0; i < n; i++ {
+		visits = append(visits, &Visit{ID: i + 1, Date: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	return visits
+}
+
+// BenchmarkInsertByDate inserts visits arriving in date order, as recorded by
+// the Middleware, restarting from an empty slice every 10000 visits
+func BenchmarkInsertByDate(b *testing.B) {
+	visits := insertBenchmarkVisits(10000)
+	sorted := make([]*Visit, 0, len(visits))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if i%len(visits) == 0 {
+			sorted = sorted[:0]
+		}
+
+		sorted = insertByDate(sorted, visits[i%len(visits)])
+	}
+}
+
+// BenchmarkInsertByDateLate inserts a visit 100 positions back in 10000, as a
+// request completing after 100 later ones
+func BenchmarkInsertByDateLate(b *testing.B) {
+	visits := insertBenchmarkVisits(10000)
+	late := &Visit{Date: visits[len(visits)-100].Date.Add(-time.Millisecond)}
+	sorted := make([]*Visit, 0, len(visits)+1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		insertByDate(append(sorted[:0], visits...), late)
+	}
+}
+
+// BenchmarkLazySort is the alternative to insertByDate for the same visit: an
+// append, then a sort before the next binary search
+func BenchmarkLazySort(b *testing.B) {
+	visits := insertBenchmarkVisits(10000)
+	late := &Visit{Date: visits[len(visits)-100].Date.Add(-time.Millisecond)}
+	sorted := make([]*Visit, 0, len(visits)+1)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		SortVisits(append(append(sorted[:0], visits...), late))
+	}
+}
+
+func TestAverageLanguagesPerVisitor(t *testing.T) {
+	for header, want := range map[string][]string{
+		"en": {"en"},
+		"en-US,en;q=0.9": {"en-us", "en"},
+		"fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5": {"fr-ch", "fr", "en"},
+		"": {},
+	} {
+		if got := declaredLanguages(header); !slices.Equal(got, want) {
+			t.Errorf("declaredLanguages(%q) = %v, want %v", header, got, want)
+		}
+	}
+
+	s := New()
+
+	if got := s.AverageLanguagesPerVisitor(); got != 0 {
+		t.Errorf("AverageLanguagesPerVisitor() = %v without visitors, want 0", got)
+	}
+
+	for i, header := range []string{"en", "en-US,en;q=0.9"} {
+		s.Record(context.Background(), VisitInput{IP: fmt.Sprintf("10.0.0.%d", i), Path: "/", CodeIssued: http.StatusOK, AcceptLanguage: header})
+	}
+
+	if got := s.AverageLanguagesPerVisitor(); got != 1.5 {
+		t.Errorf("AverageLanguagesPerVisitor() = %v, want 1.5", got)
+	}
+
+	// LanguagesCount counts the same tags
+	for language, want := range map[string]int{"en": 2, "en-us": 1} {
+		if got := s.LanguagesCount()[language]; got != want {
+			t.Errorf("%s is counted %d times, want %d", language, got, want)
+		}
+	}
+
+	if got := len(s.LanguagesCount()); got != 2 {
+		t.Errorf("LanguagesCount() has %d languages, want 2", got)
+	}
+}
+
+func TestEstimatedCurrentVisitorsWindow(t *testing.T) {
+	s := New(WithCurrentVisitorWindow(30*time.Second, time.Minute))
+	now := time.Now()
+
+	s.Import([]VisitInput{
+		// a single visit has no time spent, the minimum window keeps it
+		{Date: now.Add(-10 * time.Second), IP: "10.0.0.1", Path: "/", Type: Dynamic},
+		// gone once the minimum window has passed
+		{Date: now.Add(-40 * time.Second), IP: "10.0.0.2", Path: "/", Type: Dynamic},
+		// an average time spent of 90m is clamped to the maximum window
+		{Date: now.Add(-200 * time.Minute), IP: "10.0.0.3", Path: "/", Type: Dynamic},
+		{Date: now.Add(-20 * time.Minute), IP: "10.0.0.3", Path: "/about", Type: Dynamic},
+	})
+
+	if got := s.EstimatedCurrentVisitors(); got != 1 {
+		t.Errorf("EstimatedCurrentVisitors() = %d, want 1", got)
+	}
+}
+
+func TestPageAffinity(t *testing.T) {
+	s := New()
+
+	for ip, paths := range map[string][]string{
+		"10.0.0.1": {"/a", "/b", "/c", "/b"},
+		"10.0.0.2": {"/a", "/b"},
+		"10.0.0.3": {"/c"},
+	} {
+		for _, path := range paths {
+			recordPage(s, ip, path)
+		}
+	}
+
+	want := []PageScore{{Path: "/b", Visitors: 2, Score: 1}, {Path: "/c", Visitors: 1, Score: 0.5}}
+
+	if got := s.PageAffinity("/a", 0); !slices.Equal(got, want) {
+		t.Errorf("PageAffinity(/a) = %v, want %v", got, want)
+	}
+
+	if got := s.PageAffinity("/a", 1); len(got) != 1 || got[0].Path != "/b" {
+		t.Errorf("PageAffinity(/a, 1) = %v, want /b only", got)
+	}
+
+	if got := s.PageAffinity("/unknown", 0); len(got) != 0 {
+		t.Errorf("PageAffinity of an unknown page = %v, want none", got)
+	}
+}
@@ -0,0 +1,242 @@
+package core
+
+import (
+	"strings"
+	"slices"
+	"log/slog"
+	"time"
+)
+
+type Option func(*Statistics)
+
+// WithTrackOnlyMatchedRoutes records the visits to paths that didn't match
+// any registered route under the single UnmatchedPath page instead of
+// creating a page per requested path
+func WithTrackOnlyMatchedRoutes() Option {
+	return func(s *Statistics) {
+		s.trackOnlyMatchedRoutes = true
+	}
+}
+
+// WithDynamicOnly skips static visits entirely, they aren't added to the
+// pages, the visits nor the visitors history
+func WithDynamicOnly() Option {
+	return func(s *Statistics) {
+		s.dynamicOnly = true
+	}
+}
+
+// WithRefererBlocklist adds hosts to the default referer spam blocklist,
+// subdomains of a blocked host are blocked too
+func WithRefererBlocklist(hosts ...string) Option {
+	return func(s *Statistics) {
+		for _, host := range hosts {
+			s.refererBlocklist[strings.ToLower(host)] = true
+		}
+	}
+}
+
+// WithMaxPages caps the number of tracked pages to n, when a new page would
+// exceed it the least visited page is evicted and its visits are moved to the
+// OtherPath page, which isn't counted in n
+func WithMaxPages(n int) Option {
+	return func(s *Statistics) {
+		s.maxPages = n
+	}
+}
+
+// WithOnVisit registers a callback fired after each recorded visit, it can be
+// used several times to register several callbacks.
+// The callbacks run synchronously on the request goroutine once the statistics
+// lock is released, so they may run concurrently for different requests. They
+// get the copy of the visit returned by Record
+func WithOnVisit(onVisit func(*Visit)) Option {
+	return func(s *Statistics) {
+		s.onVisit = append(s.onVisit, onVisit)
+	}
+}
+
+// WithoutTimeSpent disables the TimeSpent tracking, which saves looking up the
+// last dynamic visit of the visitor on each request
+func WithoutTimeSpent() Option {
+	return func(s *Statistics) {
+		s.withoutTimeSpent = true
+	}
+}
+
+// WithRefererHostOnly only keeps the scheme and host of the referers so their
+// path and query, which may hold sensitive data, aren't stored
+func WithRefererHostOnly() Option {
+	return func(s *Statistics) {
+		s.refererHostOnly = true
+	}
+}
+
+// WithMaxHistoryPerVisitor only keeps the n most recent visits of each visitor,
+// the older ones are removed from the statistics while the DynamicVisits,
+// StaticVisits and VisitsCount totals keep counting them
+func WithMaxHistoryPerVisitor(n int) Option {
+	return func(s *Statistics) {
+		s.maxHistoryPerVisitor = n
+	}
+}
+
+// WithSlogLogger logs each recorded visit at debug level with its path,
+// status, loading time, visitor IP and page type
+func WithSlogLogger(logger *slog.Logger) Option {
+	return func(s *Statistics) {
+		s.logger = logger
+	}
+}
+
+// WithStaticExtensions adds file extensions (e.g. ".avif") to the default ones
+// classifying a visit as Static whatever its Content-Type
+func WithStaticExtensions(extensions ...string) Option {
+	return func(s *Statistics) {
+		for _, extension := range extensions {
+			s.staticExtensions = append(s.staticExtensions, strings.ToLower(extension))
+		}
+	}
+}
+
+// WithoutStaticExtensions removes file extensions from the static ones, e.g.
+// ".xml" for a dynamic /sitemap.xml, or all of them when none is given so
+// a following WithStaticExtensions replaces the defaults. The visits of these
+// paths are then classified by their Content-Type
+func WithoutStaticExtensions(extensions ...string) Option {
+	return func(s *Statistics) {
+		if len(extensions) == 0 {
+			s.staticExtensions = nil
+			return
+		}
+
+		for _, extension := range extensions {
+			s.staticExtensions = slices.DeleteFunc(s.staticExtensions, func(e string) bool {
+				return e == strings.ToLower(extension)
+			})
+		}
+	}
+}
+
+// WithAppUserAgents adds user agent substrings identifying the native apps and
+// SDKs consuming the API, they are matched case-insensitively
+func WithAppUserAgents(patterns ...string) Option {
+	return func(s *Statistics) {
+		for _, pattern := range patterns {
+			s.appUserAgents = append(s.appUserAgents, strings.ToLower(pattern))
+		}
+	}
+}
+
+// WithPrimaryLanguageOnly counts only the primary language of each visitor in
+// VisitorsLanguage, visitors without one are counted as UnknownLanguage, so
+// the counts sum up to the number of visitors
+func WithPrimaryLanguageOnly() Option {
+	return func(s *Statistics) {
+		s.primaryLanguageOnly = true
+	}
+}
+
+// WithWarmupPeriod flags the visits made within d of New as Visit.Warmup, they
+// are still counted but left out of the loading time averages, percentiles and
+// histograms
+func WithWarmupPeriod(d time.Duration) Option {
+	return func(s *Statistics) {
+		s.warmupPeriod = d
+	}
+}
+
+// WithIgnoredStatusCodes skips the requests answered with one of the codes
+// (e.g. 304 or 101), they aren't recorded and don't consume a visit ID
+func WithIgnoredStatusCodes(codes ...int) Option {
+	return func(s *Statistics) {
+		for _, code := range codes {
+			s.ignoredStatusCodes[code] = true
+		}
+	}
+}
+
+// WithSummaryPercentiles sets the loading time percentiles (0-100) computed by
+// Summary, 50, 90 and 99 by default
+func WithSummaryPercentiles(percentiles ...float64) Option {
+	return func(s *Statistics) {
+		s.summaryPercentiles = percentiles
+	}
+}
+
+// WithSuccessfulOnly only records the visits answered with a 2xx or 3xx code,
+// so hijacked connections aren't recorded either. The error metrics
+// (NotFoundRate, StatusCodeCounts, ThrottledVisitsCount...) then have nothing
+// to count
+func WithSuccessfulOnly() Option {
+	return func(s *Statistics) {
+		s.successfulOnly = true
+	}
+}
+
+// WithQueryStrings records the query string of each request in Visit.Query,
+// the pages stay keyed by path. Beware of the tokens some URLs carry
+func WithQueryStrings() Option {
+	return func(s *Statistics) {
+		s.queryStrings = true
+	}
+}
+
+// WithVisitorIDSalt sets the salt hashing the visitor keys into Visitor.ID,
+// a random one is used by default so the IDs change on every New. Keep the
+// salt secret and distinct per deployment
+func WithVisitorIDSalt(salt string) Option {
+	return func(s *Statistics) {
+		s.visitorIDSalt = salt
+	}
+}
+
+// WithAutosave loads the statistics from path if the file exists and writes
+// them to it every interval, Close writes them a last time
+func WithAutosave(path string, interval time.Duration) Option {
+	return func(s *Statistics) {
+		s.autosavePath = path
+		s.autosaveInterval = interval
+	}
+}
+
+// WithMetricsFlusher gives the visits count, visitors count and average loading
+// time of the visits recorded during each interval to flusher, Close flushes
+// the last interval
+func WithMetricsFlusher(flusher MetricsFlusher, interval time.Duration) Option {
+	return func(s *Statistics) {
+		s.flusher = flusher
+		s.flushInterval = interval
+	}
+}
+
+// WithDropFlushedVisits removes the visits once flushed by the MetricsFlusher
+// so they aren't kept forever, VisitsCount still counts them
+func WithDropFlushedVisits() Option {
+	return func(s *Statistics) {
+		s.dropFlushedVisits = true
+	}
+}
+
+// WithVisitStore hands the visit details to store instead of a
+// MemoryVisitStore. Only the aggregates (VisitsCount, the pages and visitors
+// counters, VisitorsLanguage...) and the last dynamic visit of each visitor,
+// whose time spent is still to be set, are then kept in memory: the Visits
+// map, Page.Visits, Visitor.History and VisitsByUser hold only these while
+// GetVisit, LastNVisits and the time range queries read from the store
+func WithVisitStore(store VisitStore) Option {
+	return func(s *Statistics) {
+		s.store = store
+		s.offloadVisits = true
+	}
+}
+
+// WithCurrentVisitorWindow clamps the average time spent after which
+// EstimatedCurrentVisitors considers a visitor gone, 30s to 30min by default.
+// A maxWindow <= 0 doesn't clamp the window
+func WithCurrentVisitorWindow(minWindow, maxWindow time.Duration) Option {
+	return func(s *Statistics) {
+		s.currentWindowMin = minWindow
+		s.currentWindowMax = maxWindow
+	}
+}
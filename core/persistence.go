@@ -0,0 +1,253 @@
+package core
+
+import (
+	"io"
+	"encoding/gob"
+	"maps"
+	"fmt"
+)
+
+type (
+	// persistedState is the pointer-free representation of the statistics,
+	// the visits reference their visitor by key and their page by path while
+	// the pages and visitors reference their visits by ID
+	persistedState struct {
+		CurrentVisitID int
+		VisitsCount int64
+		VisitorsCount int64
+		ImportedVisits int
+		ImportedVisitors int
+		RemovedVisits int
+		VisitorsLanguage map[string]int
+		Pages []persistedPage
+		Visitors []persistedVisitor
+		Visits []persistedVisit
+	}
+
+	persistedPage struct {
+		Page Page
+		VisitIDs []int
+	}
+
+	persistedVisitor struct {
+		Key string
+		Visitor Visitor
+		HistoryIDs []int
+		LastDynamicVisitID int
+	}
+
+	persistedVisit struct {
+		Visit Visit
+		VisitorKey string
+		Path string
+	}
+)
+
+// SaveBinary writes the whole statistics in the gob format, with
+// WithVisitStore the visits already offloaded to the store are left to it
+func (s *Statistics) SaveBinary(w io.Writer) error {
+	s.mutex.Lock()
+	state := s.persistedState()
+	s.mutex.Unlock()
+
+	return gob.NewEncoder(w).Encode(state)
+}
+
+// LoadBinary replaces the statistics with the ones written by SaveBinary, the
+// options of s are kept
+func (s *Statistics) LoadBinary(r io.Reader) error {
+	var state persistedState
+
+	if err := gob.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.restore(state)
+}
+
+func (s *Statistics) persistedState() persistedState {
+	state := persistedState{
+		CurrentVisitID: int(s.currentVisitID.Load()),
+		VisitsCount: s.visitsCount.Load(),
+		VisitorsCount: s.visitorsCount.Load(),
+		ImportedVisits: s.importedVisits,
+		ImportedVisitors: s.importedVisitors,
+		RemovedVisits: s.removedVisits,
+		// SaveBinary encodes the state once the mutex is released
+		VisitorsLanguage: maps.Clone(s.VisitorsLanguage),
+		Pages: make([]persistedPage, 0, len(s.Pages)),
+		Visitors: make([]persistedVisitor, 0, len(s.Visitors)),
+		Visits: make([]persistedVisit, 0, len(s.Visits)),
+	}
+
+	ids := func(visits []*Visit) []int {
+		visitIDs := make([]int, 0, len(visits))
+
+		for _, v := range visits {
+			visitIDs = append(visitIDs, v.ID)
+		}
+
+		return visitIDs
+	}
+
+	keys := make(map[*Visitor]string, len(s.Visitors))
+
+	for _, key := range s.sortedVisitorKeys() {
+		visitor := s.Visitors[key]
+		keys[visitor] = key
+
+		persisted := persistedVisitor{
+			Key: key,
+			Visitor: *shallowVisitor(visitor),
+			HistoryIDs: ids(visitor.History),
+		}
+
+		if visitor.lastDynamicVisit != nil {
+			persisted.LastDynamicVisitID = visitor.lastDynamicVisit.ID
+		}
+
+		state.Visitors = append(state.Visitors, persisted)
+	}
+
+	for _, path := range s.sortedPagePaths() {
+		state.Pages = append(state.Pages, persistedPage{
+			Page: *shallowPage(s.Pages[path]),
+			VisitIDs: ids(s.Pages[path].Visits),
+		})
+	}
+
+	for _, id := range s.sortedVisitIDs() {
+		v := s.Visits[id]
+
+		visit := *v
+		visit.VisitedBy = nil
+		visit.Page = nil
+
+		state.Visits = append(state.Visits, persistedVisit{
+			Visit: visit,
+			VisitorKey: keys[v.VisitedBy],
+			Path: v.Page.Path,
+		})
+	}
+
+	return state
+}
+
+// restore rebuilds the pointer graph of the persisted state in place of the
+// current statistics
+func (s *Statistics) restore(state persistedState) error {
+	pages := make(map[string]*Page, len(state.Pages))
+	visitors := make(map[string]*Visitor, len(state.Visitors))
+	visits := make(map[int]*Visit, len(state.Visits))
+
+	for _, p := range state.Pages {
+		page := p.Page
+		pages[page.Path] = &page
+	}
+
+	for _, v := range state.Visitors {
+		visitor := v.Visitor
+		// the IDs follow the salt of s
+		visitor.ID = s.visitorID(v.Key)
+		visitors[v.Key] = &visitor
+	}
+
+	for _, v := range state.Visits {
+		visit := v.Visit
+		visit.VisitedBy = visitors[v.VisitorKey]
+		visit.Page = pages[v.Path]
+
+		if visit.VisitedBy == nil || visit.Page == nil {
+			return fmt.Errorf("visit %d references an unknown visitor or page", visit.ID)
+		}
+
+		visits[visit.ID] = &visit
+	}
+
+	lookup := func(ids []int) ([]*Visit, error) {
+		found := make([]*Visit, 0, len(ids))
+
+		for _, id := range ids {
+			visit, ok := visits[id]
+			if !ok {
+				return nil, fmt.Errorf("unknown visit %d", id)
+			}
+
+			found = append(found, visit)
+		}
+
+		return found, nil
+	}
+
+	var err error
+
+	// files saved before the visits were inserted in date order may hold
+	// unsorted visits
+	for _, p := range state.Pages {
+		if pages[p.Page.Path].Visits, err = lookup(p.VisitIDs); err != nil {
+			return err
+		}
+
+		SortVisits(pages[p.Page.Path].Visits)
+	}
+
+	for _, v := range state.Visitors {
+		if visitors[v.Key].History, err = lookup(v.HistoryIDs); err != nil {
+			return err
+		}
+
+		SortVisits(visitors[v.Key].History)
+
+		visitors[v.Key].lastDynamicVisit = visits[v.LastDynamicVisitID]
+	}
+
+	s.Pages = pages
+	s.Visitors = visitors
+	s.Visits = visits
+	s.VisitorsLanguage = state.VisitorsLanguage
+	s.Users = make(map[string][]*Visit)
+
+	// the store of WithVisitStore keeps the visits offloaded before the save,
+	// the loaded ones are added to it again
+	if !s.offloadVisits {
+		s.store = NewMemoryVisitStore()
+	}
+
+	if s.VisitorsLanguage == nil {
+		s.VisitorsLanguage = make(map[string]int)
+	}
+
+	ordered := make([]*Visit, 0, len(visits))
+
+	for _, visit := range visits {
+		ordered = append(ordered, visit)
+	}
+
+	SortVisits(ordered)
+
+	for _, visit := range ordered {
+		if visit.UserID != "" {
+			s.Users[visit.UserID] = append(s.Users[visit.UserID], visit)
+		}
+
+		s.store.Add(visit)
+	}
+
+	if s.maxPages > 0 {
+		s.rebuildLeastVisited()
+	}
+
+	s.currentVisitID.Store(int64(state.CurrentVisitID))
+	s.visitsCount.Store(state.VisitsCount)
+	s.visitorsCount.Store(state.VisitorsCount)
+	s.importedVisits = state.ImportedVisits
+	s.importedVisitors = state.ImportedVisitors
+	s.removedVisits = state.RemovedVisits
+	// createdAt, the start of the warmup period, stays the one of this process
+	// so WithWarmupPeriod still applies once the autosave is loaded by New
+
+	return nil
+}
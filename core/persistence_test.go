@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+const benchmarkPersistenceVisits = 50000
+
+// persistenceFixture returns statistics of benchmarkPersistenceVisits visits
+// spread over 1000 visitors and 100 pages
+func persistenceFixture(tb testing.TB) *Statistics {
+	s := New()
+	start := time.Now().Add(-24 * time.Hour)
+	inputs := make([]VisitInput, 0, benchmarkPersistenceVisits)
+
+	for i := 0; i < benchmarkPersistenceVisits; i++ {
+		inputs = append(inputs, VisitInput{
+			Date: start.Add(time.Duration(i) * time.Second),
+			IP: fmt.Sprintf("10.0.%d.%d", i%1000/256, i%1000%256),
+			Path: fmt.Sprintf("/page/%d", i%100),
+			CodeIssued: 200,
+			LoadingTime: time.Duration(i%500) * time.Millisecond,
+			ContentType: "text/html; charset=utf-8",
+			UserAgent: "Mozilla/5.0 (X11; Linux x86_64)",
+			AcceptLanguage: "fr-CH, fr;q=0.9, en;q=0.8",
+			Referer: "https://example.com/search?q=statistics",
+		})
+	}
+
+	if err := s.Import(inputs); err != nil {
+		tb.Fatal(err)
+	}
+
+	return s
+}
+
+func TestSaveBinaryRoundTrip(t *testing.T) {
+	s := persistenceFixture(t)
+	buffer := &bytes.Buffer{}
+
+	if err := s.SaveBinary(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+
+	if err := loaded.LoadBinary(buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loaded.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.VisitsCount() != s.VisitsCount() || loaded.VisitorsCount() != s.VisitorsCount() || loaded.PageCount() != s.PageCount() {
+		t.Errorf("loaded %d visits, %d visitors and %d pages, want %d, %d and %d", loaded.VisitsCount(), loaded.VisitorsCount(), loaded.PageCount(), s.VisitsCount(), s.VisitorsCount(), s.PageCount())
+	}
+}
+
+// TestSaveBinaryDuringWrites saves while visits are recorded, run with -race
+func TestSaveBinaryDuringWrites(t *testing.T) {
+	s := New()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 500; i++ {
+			s.Record(context.Background(), VisitInput{IP: fmt.Sprintf("10.0.0.%d", i), Path: "/", CodeIssued: 200, AcceptLanguage: "fr-CH, fr;q=0.9, en;q=0.8"})
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if err := s.SaveBinary(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// benchmarkSave reports the size of the saved statistics per visit
+func benchmarkSave(b *testing.B, save func(s *Statistics, buffer *bytes.Buffer) error) {
+	s := persistenceFixture(b)
+	buffer := &bytes.Buffer{}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+
+		if err := save(s, buffer); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(buffer.Len())/benchmarkPersistenceVisits, "bytes/visit")
+}
+
+func benchmarkLoad(b *testing.B, save func(s *Statistics, buffer *bytes.Buffer) error, load func(s *Statistics, data []byte) error) {
+	buffer := &bytes.Buffer{}
+
+	if err := save(persistenceFixture(b), buffer); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := load(New(), buffer.Bytes()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func saveBinary(s *Statistics, buffer *bytes.Buffer) error {
+	return s.SaveBinary(buffer)
+}
+
+func saveJSONL(s *Statistics, buffer *bytes.Buffer) error {
+	return s.ExportJSONL(buffer)
+}
+
+func BenchmarkSaveBinary(b *testing.B) {
+	benchmarkSave(b, saveBinary)
+}
+
+func BenchmarkSaveJSONL(b *testing.B) {
+	benchmarkSave(b, saveJSONL)
+}
+
+func BenchmarkLoadBinary(b *testing.B) {
+	benchmarkLoad(b, saveBinary, func(s *Statistics, data []byte) error {
+		return s.LoadBinary(bytes.NewReader(data))
+	})
+}
+
+func BenchmarkLoadJSONL(b *testing.B) {
+	benchmarkLoad(b, saveJSONL, func(s *Statistics, data []byte) error {
+		return s.ImportJSONL(bytes.NewReader(data))
+	})
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"time"
+	"slices"
+)
+
+// RangeStats is a view of the statistics restricted to the visits of a time
+// range, it is computed once by Statistics.RangeStats and doesn't change
+// afterwards
+type RangeStats struct {
+	Start time.Time
+	End time.Time
+
+	visits []*Visit
+	visitors map[string]*Visitor
+	pages []PageCount
+	languages map[string]int
+}
+
+// RangeStats returns the view of the visits whose date is in [start, end)
+func (s *Statistics) RangeStats(start, end time.Time) *RangeStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := &RangeStats{
+		Start: start,
+		End: end,
+		visits: s.detachStored(s.visitsBetween(start, end)),
+		visitors: make(map[string]*Visitor),
+		pages: []PageCount{},
+		languages: make(map[string]int),
+	}
+
+	pageCounts := make(map[string]int)
+
+	for _, v := range r.visits {
+		r.visitors[visitorKey(v.VisitedBy)] = v.VisitedBy
+		pageCounts[v.Page.Path]++
+	}
+
+	for path, count := range pageCounts {
+		r.pages = append(r.pages, PageCount{
+			Path: path,
+			Count: count,
+		})
+	}
+
+	sortPageCounts(r.pages)
+
+	for _, visitor := range r.visitors {
+		for _, l := range s.countedLanguages(visitor.Language) {
+			r.languages[l]++
+		}
+	}
+
+	return r
+}
+
+func (r *RangeStats) Visits() []*Visit {
+	return slices.Clone(r.visits)
+}
+
+func (r *RangeStats) VisitsCount() int {
+	return len(r.visits)
+}
+
+func (r *RangeStats) VisitorsCount() int {
+	return len(r.visitors)
+}
+
+// MostVisitedPages returns the visits count of each page within the range by
+// descending count
+func (r *RangeStats) MostVisitedPages() []PageCount {
+	return slices.Clone(r.pages)
+}
+
+// LanguagesCount counts the languages of the visitors seen within the range
+func (r *RangeStats) LanguagesCount() map[string]int {
+	counts := make(map[string]int, len(r.languages))
+
+	for language, count := range r.languages {
+		counts[language] = count
+	}
+
+	return counts
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Report holds the main aggregates of the statistics computed from the same
+// state, see ComputeAll
+type Report struct {
+	VisitsCount int
+	VisitorsCount int
+	PagesCount int
+	AverageLoadingTime time.Duration
+	LoadingTimePercentiles []Percentile
+	BounceRate float64
+	DirectTrafficShare float64
+	CompressedShare float64
+	Languages map[string]int
+	Referers map[string]int
+	StatusCodes map[int]int
+	VisitsByType map[PageType]int
+	ClientClasses map[string]int
+	// PeakHour is the start of the hour window holding the most visits
+	PeakHour time.Time
+	PeakHourVisits int
+}
+
+// ComputeAll computes the Report in a single pass over the visits under the
+// lock, cheaper and more consistent than calling each method
+func (s *Statistics) ComputeAll() *Report {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &Report{
+		VisitsCount: s.VisitsCount(),
+		VisitorsCount: s.VisitorsCount(),
+		PagesCount: len(s.Pages),
+		BounceRate: s.bounceRate(),
+		LoadingTimePercentiles: []Percentile{},
+		Languages: maps.Clone(s.VisitorsLanguage),
+		Referers: make(map[string]int),
+		StatusCodes: make(map[int]int),
+		VisitsByType: make(map[PageType]int),
+		ClientClasses: make(map[string]int),
+	}
+
+	direct := 0
+	compressed := 0
+	samples := []time.Duration{}
+	totalLoadingTime := time.Duration(0)
+
+	visits := s.store.All()
+
+	for _, v := range visits {
+		if host := refererHost(v.Referer); host != "" {
+			report.Referers[host]++
+		}
+
+		if v.Referer == "" {
+			direct++
+		}
+
+		if v.Compressed {
+			compressed++
+		}
+
+		if v.isLoadingTimeSample() {
+			samples = append(samples, v.LoadingTime)
+			totalLoadingTime += v.LoadingTime
+		}
+
+		report.StatusCodes[v.CodeIssued]++
+		report.VisitsByType[v.Type]++
+		report.ClientClasses[v.ClientClass]++
+	}
+
+	if len(visits) > 0 {
+		report.DirectTrafficShare = float64(direct) / float64(len(visits))
+		report.CompressedShare = float64(compressed) / float64(len(visits))
+	}
+
+	if len(samples) > 0 {
+		report.AverageLoadingTime = totalLoadingTime / time.Duration(len(samples))
+	}
+
+	// the visit log is sorted by date, not by loading time
+	slices.Sort(samples)
+
+	for _, p := range s.summaryPercentiles {
+		report.LoadingTimePercentiles = append(report.LoadingTimePercentiles, Percentile{
+			Percentile: p,
+			Value: percentile(samples, p),
+		})
+	}
+
+	report.PeakHour, report.PeakHourVisits = s.peakWindow(time.Hour)
+
+	return report
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"cmp"
+	"slices"
+)
+
+// DefineSegment names a group of visitors, e.g. "power users" with
+// func(v *Visitor) bool { return v.VisitsCount() > 20 }, defining an existing
+// name replaces its predicate
+func (s *Statistics) DefineSegment(name string, pred func(*Visitor) bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.segments == nil {
+		s.segments = make(map[string]func(*Visitor) bool)
+	}
+
+	s.segments[name] = pred
+}
+
+// Segment returns the current visitors matching the predicate of the segment
+// sorted by IP then fingerprint, it is empty for an undefined segment
+func (s *Statistics) Segment(name string) []*Visitor {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitors := []*Visitor{}
+
+	pred, ok := s.segments[name]
+	if !ok {
+		return visitors
+	}
+
+	for _, v := range s.Visitors {
+		if pred(v) {
+			visitors = append(visitors, v)
+		}
+	}
+
+	slices.SortFunc(visitors, func(a, b *Visitor) int {
+		return cmp.Or(cmp.Compare(a.IP, b.IP), cmp.Compare(a.Fingerprint, b.Fingerprint))
+	})
+
+	return detachVisitors(visitors)
+}
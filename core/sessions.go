@@ -0,0 +1,70 @@
+package core
+
+import (
+	"time"
+)
+
+// Sessions splits the dynamic visits of the visitor into sessions, a new one
+// starts when more than timeout elapsed since the previous dynamic visit
+func (v *Visitor) Sessions(timeout time.Duration) [][]*Visit {
+	return splitSessions(v.History, timeout)
+}
+
+func splitSessions(history []*Visit, timeout time.Duration) [][]*Visit {
+	sessions := [][]*Visit{}
+
+	var last *Visit
+
+	for _, v := range history {
+		if v.Type != Dynamic {
+			continue
+		}
+
+		if last == nil || v.Date.Sub(last.Date) > timeout {
+			sessions = append(sessions, []*Visit{})
+		}
+
+		sessions[len(sessions)-1] = append(sessions[len(sessions)-1], v)
+		last = v
+	}
+
+	return sessions
+}
+
+// VisitorSessionCount returns the number of sessions of the visitor computed
+// under the lock, or 0 for an unknown visitor
+func (s *Statistics) VisitorSessionCount(ip string, timeout time.Duration) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	visitor, ok := s.Visitors[normalizeIP(ip)]
+	if !ok {
+		return 0
+	}
+
+	return len(splitSessions(visitor.History, timeout))
+}
+
+// SessionLengthHistogram counts the sessions by number of page views, a
+// session falls in the first of the ascending buckets greater or equal to its
+// length and the last count holds the sessions longer than every bucket
+func (s *Statistics) SessionLengthHistogram(timeout time.Duration, buckets []int) []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make([]int, len(buckets)+1)
+
+	for _, visitor := range s.Visitors {
+		for _, session := range splitSessions(visitor.History, timeout) {
+			i := 0
+
+			for i < len(buckets) && len(session) > buckets[i] {
+				i++
+			}
+
+			counts[i]++
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"slices"
+	"maps"
+)
+
+type StatsDiff struct {
+	Visits int
+	Visitors int
+	// Pages holds the visits count delta of every page present in either
+	// snapshot, a page missing from one of them counts as 0 visits there
+	Pages map[string]int
+	NewPages []string
+	RemovedPages []string
+}
+
+// Snapshot returns a deep copy of the statistics, it is detached from the
+// Middleware so its pages, visitors and visits can be read without racing
+func (s *Statistics) Snapshot() *Statistics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot := &Statistics{
+		Visitors: make(map[string]*Visitor, len(s.Visitors)),
+		Pages: make(map[string]*Page, len(s.Pages)),
+		Visits: make(map[int]*Visit, len(s.Visits)),
+		VisitorsLanguage: make(map[string]int, len(s.VisitorsLanguage)),
+		Users: make(map[string][]*Visit, len(s.Users)),
+		importedVisits: s.importedVisits,
+		importedVisitors: s.importedVisitors,
+		removedVisits: s.removedVisits,
+		createdAt: s.createdAt,
+		segments: maps.Clone(s.segments),
+		settings: s.settings,
+	}
+
+	snapshot.currentVisitID.Store(s.currentVisitID.Load())
+	snapshot.visitsCount.Store(s.visitsCount.Load())
+	snapshot.visitorsCount.Store(s.visitorsCount.Load())
+
+	pages := make(map[*Page]*Page, len(s.Pages))
+	visitors := make(map[*Visitor]*Visitor, len(s.Visitors))
+	visits := make(map[*Visit]*Visit, len(s.Visits))
+
+	copyPage := func(page *Page) *Page {
+		if _, ok := pages[page]; !ok {
+			pages[page] = shallowPage(page)
+		}
+
+		return pages[page]
+	}
+
+	copyVisitor := func(visitor *Visitor) *Visitor {
+		if _, ok := visitors[visitor]; !ok {
+			visitors[visitor] = shallowVisitor(visitor)
+		}
+
+		return visitors[visitor]
+	}
+
+	copyVisits := func(from []*Visit) []*Visit {
+		to := make([]*Visit, 0, len(from))
+
+		for _, v := range from {
+			to = append(to, visits[v])
+		}
+
+		return to
+	}
+
+	for id, v := range s.Visits {
+		visit := *v
+		visit.VisitedBy = copyVisitor(v.VisitedBy)
+		visit.Page = copyPage(v.Page)
+
+		visits[v] = &visit
+		snapshot.Visits[id] = &visit
+	}
+
+	for path, page := range s.Pages {
+		snapshot.Pages[path] = copyPage(page)
+		snapshot.Pages[path].Visits = copyVisits(page.Visits)
+	}
+
+	for key, visitor := range s.Visitors {
+		snapshot.Visitors[key] = copyVisitor(visitor)
+		snapshot.Visitors[key].History = copyVisits(visitor.History)
+		snapshot.Visitors[key].lastDynamicVisit = visits[visitor.lastDynamicVisit]
+	}
+
+	for language, count := range s.VisitorsLanguage {
+		snapshot.VisitorsLanguage[language] = count
+	}
+
+	for userID, userVisits := range s.Users {
+		snapshot.Users[userID] = copyVisits(userVisits)
+	}
+
+	// the snapshot keeps its visits in memory whatever the store of s, the
+	// ones the store rebuilt are linked to the copies by path and visitor key
+	snapshot.store = NewMemoryVisitStore()
+
+	for _, v := range s.store.All() {
+		visit, ok := snapshot.Visits[v.ID]
+
+		if !ok {
+			copied := *v
+			copied.Page = snapshot.Pages[v.Page.Path]
+			copied.VisitedBy = snapshot.Visitors[visitorKey(v.VisitedBy)]
+
+			if copied.Page == nil {
+				copied.Page = shallowPage(v.Page)
+			}
+
+			if copied.VisitedBy == nil {
+				copied.VisitedBy = shallowVisitor(v.VisitedBy)
+			}
+
+			visit = &copied
+		}
+
+		snapshot.store.Add(visit)
+	}
+
+	return snapshot
+}
+
+// shallowPage copies the page without its visits
+func shallowPage(page *Page) *Page {
+	return &Page{
+		Path: page.Path,
+		FirstSeen: page.FirstSeen,
+		PriorVisits: page.PriorVisits,
+	}
+}
+
+// shallowVisitor copies the visitor without its history
+func shallowVisitor(visitor *Visitor) *Visitor {
+	return &Visitor{
+		ID: visitor.ID,
+		IP: visitor.IP,
+		Fingerprint: visitor.Fingerprint,
+		Language: visitor.Language,
+		FirstSeen: visitor.FirstSeen,
+		DynamicVisits: visitor.DynamicVisits,
+		StaticVisits: visitor.StaticVisits,
+	}
+}
+
+// detachVisit copies the visit, its VisitedBy and Page are copies without
+// their history and visits
+func detachVisit(visit *Visit) *Visit {
+	detached := *visit
+	detached.VisitedBy = shallowVisitor(visit.VisitedBy)
+	detached.Page = shallowPage(visit.Page)
+
+	return &detached
+}
+
+// detachVisits copies the visits as detachVisit, the visits of a visitor or of
+// a page sharing its copy
+func detachVisits(visits []*Visit) []*Visit {
+	pages := make(map[*Page]*Page)
+	visitors := make(map[*Visitor]*Visitor)
+	detached := make([]*Visit, 0, len(visits))
+
+	for _, v := range visits {
+		if _, ok := pages[v.Page]; !ok {
+			pages[v.Page] = shallowPage(v.Page)
+		}
+
+		if _, ok := visitors[v.VisitedBy]; !ok {
+			visitors[v.VisitedBy] = shallowVisitor(v.VisitedBy)
+		}
+
+		visit := *v
+		visit.Page = pages[v.Page]
+		visit.VisitedBy = visitors[v.VisitedBy]
+
+		detached = append(detached, &visit)
+	}
+
+	return detached
+}
+
+// detachStored copies the visits returned by the store as detachVisits, their
+// page and visitor are looked up by path and visitor key as the store may
+// have rebuilt them with only these
+func (s *Statistics) detachStored(visits []*Visit) []*Visit {
+	pages := make(map[string]*Page)
+	visitors := make(map[string]*Visitor)
+	detached := make([]*Visit, 0, len(visits))
+
+	for _, v := range visits {
+		path, key := v.Page.Path, visitorKey(v.VisitedBy)
+
+		if _, ok := pages[path]; !ok {
+			page, ok := s.Pages[path]
+			if !ok {
+				page = v.Page
+			}
+
+			pages[path] = shallowPage(page)
+		}
+
+		if _, ok := visitors[key]; !ok {
+			visitor, ok := s.Visitors[key]
+			if !ok {
+				visitor = v.VisitedBy
+			}
+
+			visitors[key] = shallowVisitor(visitor)
+		}
+
+		visit := *v
+		visit.Page = pages[path]
+		visit.VisitedBy = visitors[key]
+
+		detached = append(detached, &visit)
+	}
+
+	return detached
+}
+
+// detachPages copies the pages as detachPage
+func detachPages(pages []*Page) []*Page {
+	detached := make([]*Page, 0, len(pages))
+
+	for _, page := range pages {
+		detached = append(detached, detachPage(page))
+	}
+
+	return detached
+}
+
+// detachVisitors copies the visitors as detachVisitor
+func detachVisitors(visitors []*Visitor) []*Visitor {
+	detached := make([]*Visitor, 0, len(visitors))
+
+	for _, visitor := range visitors {
+		detached = append(detached, detachVisitor(visitor))
+	}
+
+	return detached
+}
+
+// detachPage copies the page and its visits, the visitors of the visits are
+// shallow copies
+func detachPage(page *Page) *Page {
+	detached := shallowPage(page)
+	visitors := make(map[*Visitor]*Visitor)
+
+	for _, v := range page.Visits {
+		if _, ok := visitors[v.VisitedBy]; !ok {
+			visitors[v.VisitedBy] = shallowVisitor(v.VisitedBy)
+		}
+
+		visit := *v
+		visit.Page = detached
+		visit.VisitedBy = visitors[v.VisitedBy]
+
+		detached.Visits = append(detached.Visits, &visit)
+	}
+
+	return detached
+}
+
+// detachVisitor copies the visitor and its history, the pages of the visits
+// are shallow copies
+func detachVisitor(visitor *Visitor) *Visitor {
+	detached := shallowVisitor(visitor)
+	pages := make(map[*Page]*Page)
+
+	for _, v := range visitor.History {
+		if _, ok := pages[v.Page]; !ok {
+			pages[v.Page] = shallowPage(v.Page)
+		}
+
+		visit := *v
+		visit.Page = pages[v.Page]
+		visit.VisitedBy = detached
+
+		detached.History = append(detached.History, &visit)
+
+		if v == visitor.lastDynamicVisit {
+			detached.lastDynamicVisit = &visit
+		}
+	}
+
+	return detached
+}
+
+// Diff returns the changes from snapshot a to snapshot b
+func Diff(a, b *Statistics) StatsDiff {
+	before := a.ExportAggregates()
+	after := b.ExportAggregates()
+
+	diff := StatsDiff{
+		Visits: after.TotalVisits - before.TotalVisits,
+		Visitors: after.TotalVisitors - before.TotalVisitors,
+		Pages: make(map[string]int),
+		NewPages: []string{},
+		RemovedPages: []string{},
+	}
+
+	for path, count := range after.PageVisits {
+		if _, ok := before.PageVisits[path]; !ok {
+			diff.NewPages = append(diff.NewPages, path)
+		}
+
+		diff.Pages[path] = count - before.PageVisits[path]
+	}
+
+	for path, count := range before.PageVisits {
+		if _, ok := after.PageVisits[path]; !ok {
+			diff.RemovedPages = append(diff.RemovedPages, path)
+			diff.Pages[path] = -count
+		}
+	}
+
+	slices.Sort(diff.NewPages)
+	slices.Sort(diff.RemovedPages)
+
+	return diff
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestCopiesKeepVisitorID(t *testing.T) {
+	s := New(WithVisitorIDSalt("salt"))
+	visit := recordPage(s, "10.0.0.1", "/")
+	want := s.visitorID("10.0.0.1")
+
+	if got := s.Snapshot().VisitorSummaries()[0].ID; got != want {
+		t.Errorf("Snapshot().VisitorSummaries() ID = %q, want %q", got, want)
+	}
+
+	if got := s.GetVisitor("10.0.0.1").ID; got != want {
+		t.Errorf("GetVisitor().ID = %q, want %q", got, want)
+	}
+
+	if got := s.GetVisit(visit.ID).ToDTO().VisitorID; got != want {
+		t.Errorf("GetVisit().ToDTO().VisitorID = %q, want %q", got, want)
+	}
+
+	if got := s.GetPage("/").Visits[0].VisitedBy.ID; got != want {
+		t.Errorf("GetPage() visitor ID = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"net/http"
+	"time"
+)
+
+func (s *Statistics) StatusCodeCounts() map[int]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[int]int)
+
+	for _, v := range s.Visits {
+		counts[v.CodeIssued]++
+	}
+
+	return counts
+}
+
+func (s *Statistics) ThrottledVisitsCount() int {
+	return s.StatusCodeCounts()[http.StatusTooManyRequests]
+}
+
+// MostThrottledPages returns the n pages (all of them if n <= 0) that issued
+// the most 429 responses, pages that never did are left out
+func (s *Statistics) MostThrottledPages(n int) []PageCount {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pages := []PageCount{}
+
+	for path, page := range s.Pages {
+		if count := page.ThrottledVisitsCount(); count > 0 {
+			pages = append(pages, PageCount{
+				Path: path,
+				Count: count,
+			})
+		}
+	}
+
+	sortPageCounts(pages)
+
+	if n > 0 && n < len(pages) {
+		pages = pages[:n]
+	}
+
+	return pages
+}
+
+func (p *Page) StatusCodeCounts() map[int]int {
+	counts := make(map[int]int)
+
+	for _, v := range p.Visits {
+		counts[v.CodeIssued]++
+	}
+
+	return counts
+}
+
+func (p *Page) ThrottledVisitsCount() int {
+	return p.StatusCodeCounts()[http.StatusTooManyRequests]
+}
+
+// AverageLoadingTimeByStatusClass returns the average loading time of the page
+// per status class (2 for 2xx...), warmup and hijacked visits excepted
+func (p *Page) AverageLoadingTimeByStatusClass() map[int]time.Duration {
+	totals := make(map[int]time.Duration)
+	counts := make(map[int]int)
+
+	for _, v := range p.Visits {
+		if !v.Warmup && v.CodeIssued != StatusHijacked {
+			totals[v.CodeIssued/100] += v.LoadingTime
+			counts[v.CodeIssued/100]++
+		}
+	}
+
+	averages := make(map[int]time.Duration, len(counts))
+
+	for class, count := range counts {
+		averages[class] = totals[class] / time.Duration(count)
+	}
+
+	return averages
+}
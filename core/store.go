@@ -0,0 +1,96 @@
+package core
+
+import (
+	"slices"
+	"time"
+)
+
+type (
+	// VisitStore owns the visit details, GetVisit, LastNVisits and the time
+	// range queries (VisitsBetween, RangeStats, PeakWindow...) read from it.
+	// Its methods are called with the statistics locked so they don't need to
+	// lock.
+	//
+	// Add is given the statistics' own visit, a store serializing it mustn't
+	// keep the pointer. The visits it returns may be rebuilt values: their
+	// Page only needs its Path and their VisitedBy its IP, Fingerprint and ID,
+	// the statistics look the page and the visitor up by these
+	VisitStore interface {
+		// Add stores the visit once recorded, it is called again with the same
+		// ID when the visit changes, e.g. once its TimeSpent is known
+		Add(visit *Visit)
+		Remove(visit *Visit)
+		// Get returns the visit of the ID, false if there isn't any
+		Get(id int) (*Visit, bool)
+		// Between returns the visits whose date is in [start, end) sorted by
+		// date
+		Between(start, end time.Time) []*Visit
+		// Last returns the n most recent visits sorted by date, all of them if
+		// there are fewer
+		Last(n int) []*Visit
+		// All returns every visit sorted by date
+		All() []*Visit
+	}
+
+	// MemoryVisitStore is the default VisitStore, a slice sorted by date
+	// indexed by ID
+	MemoryVisitStore struct {
+		visits []*Visit
+		ids map[int]*Visit
+	}
+)
+
+func NewMemoryVisitStore() *MemoryVisitStore {
+	return &MemoryVisitStore{
+		ids: make(map[int]*Visit),
+	}
+}
+
+func (m *MemoryVisitStore) Add(visit *Visit) {
+	if stored, ok := m.ids[visit.ID]; ok && stored == visit {
+		return
+	} else if ok {
+		m.Remove(stored)
+	}
+
+	m.visits = insertByDate(m.visits, visit)
+	m.ids[visit.ID] = visit
+}
+
+func (m *MemoryVisitStore) Remove(visit *Visit) {
+	stored, ok := m.ids[visit.ID]
+	if !ok {
+		return
+	}
+
+	if i := indexByDate(m.visits, stored); i >= 0 {
+		m.visits = slices.Delete(m.visits, i, i+1)
+	}
+
+	delete(m.ids, visit.ID)
+}
+
+func (m *MemoryVisitStore) Get(id int) (*Visit, bool) {
+	visit, ok := m.ids[id]
+
+	return visit, ok
+}
+
+func (m *MemoryVisitStore) Between(start, end time.Time) []*Visit {
+	from, _ := slices.BinarySearchFunc(m.visits, start, compareVisitDate)
+	to, _ := slices.BinarySearchFunc(m.visits, end, compareVisitDate)
+
+	if to < from {
+		return []*Visit{}
+	}
+
+	return slices.Clone(m.visits[from:to])
+}
+
+func (m *MemoryVisitStore) Last(n int) []*Visit {
+	return append([]*Visit{}, m.visits[len(m.visits)-min(max(n, 0), len(m.visits)):]...)
+}
+
+func (m *MemoryVisitStore) All() []*Visit {
+	return append([]*Visit{}, m.visits...)
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+const benchmarkStoreSize = 1000000
+
+// benchmarkVisits returns visits one second apart in a map, as in the Visits
+// map, and in a MemoryVisitStore
+func benchmarkVisits() (map[int]*Visit, *MemoryVisitStore, time.Time) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	visits := make(map[int]*Visit, benchmarkStoreSize)
+	store := NewMemoryVisitStore()
+
+	for i := 1; i <= benchmarkStoreSize; i++ {
+		visit := &Visit{ID: i, Date: start.Add(time.Duration(i) * time.Second)}
+		visits[i] = visit
+		store.Add(visit)
+	}
+
+	return visits, store, start
+}
+
+func TestMemoryVisitStoreBetween(t *testing.T) {
+	store := NewMemoryVisitStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// added out of order, as imported visits can be
+	for id, second := range []int{3, 1, 2, 2, 5, 4} {
+		store.Add(&Visit{ID: id + 1, Date: start.Add(time.Duration(second) * time.Second)})
+	}
+
+	between := store.Between(start.Add(2*time.Second), start.Add(4*time.Second))
+
+	if len(between) != 3 {
+		t.Fatalf("Between returned %d visits, want 3", len(between))
+	}
+
+	for i, want := range []int{2, 2, 3} {
+		if got := between[i].Date.Sub(start); got != time.Duration(want)*time.Second {
+			t.Errorf("visit %d is at %v, want %ds", i, got, want)
+		}
+	}
+
+	if got := len(store.Between(start.Add(time.Hour), start)); got != 0 {
+		t.Errorf("an inverted range returned %d visits", got)
+	}
+}
+
+func TestMemoryVisitStoreAddReplaces(t *testing.T) {
+	store := NewMemoryVisitStore()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store.Add(&Visit{ID: 1, Date: start})
+	store.Add(&Visit{ID: 1, Date: start, TimeSpent: time.Minute})
+
+	if visit, ok := store.Get(1); len(store.All()) != 1 || !ok || visit.TimeSpent != time.Minute {
+		t.Errorf("adding the visit again didn't replace it: %v", store.All())
+	}
+
+	store.Remove(&Visit{ID: 1})
+
+	if _, ok := store.Get(1); ok || len(store.All()) != 0 {
+		t.Errorf("a copy of the visit didn't remove it")
+	}
+}
+
+// rowStore is a VisitStore keeping the visits as JSON, as a database would,
+// so the visits it returns are rebuilt ones
+type rowStore struct {
+	rows map[int][]byte
+}
+
+func (r *rowStore) Add(visit *Visit) {
+	r.rows[visit.ID], _ = json.Marshal(visit.exportRow())
+}
+
+func (r *rowStore) Remove(visit *Visit) {
+	delete(r.rows, visit.ID)
+}
+
+func (r *rowStore) Get(id int) (*Visit, bool) {
+	data, ok := r.rows[id]
+	if !ok {
+		return nil, false
+	}
+
+	var row visitRow
+	json.Unmarshal(data, &row)
+
+	visit := &Visit{
+		ID: row.ID,
+		Date: row.Date,
+		Type: row.Type,
+		TimeSpent: row.TimeSpent,
+		LoadingTime: row.LoadingTime,
+		CodeIssued: row.CodeIssued,
+		ConcretePath: row.ConcretePath,
+		Page: &Page{Path: row.Path},
+		VisitedBy: &Visitor{ID: row.VisitorID, IP: row.VisitorIP, Fingerprint: row.VisitorFingerprint},
+	}
+
+	return visit, true
+}
+
+func (r *rowStore) All() []*Visit {
+	visits := []*Visit{}
+
+	for id := range r.rows {
+		visit, _ := r.Get(id)
+		visits = append(visits, visit)
+	}
+
+	SortVisits(visits)
+
+	return visits
+}
+
+func (r *rowStore) Between(start, end time.Time) []*Visit {
+	return slices.DeleteFunc(r.All(), func(v *Visit) bool {
+		return v.Date.Before(start) || !v.Date.Before(end)
+	})
+}
+
+func (r *rowStore) Last(n int) []*Visit {
+	visits := r.All()
+
+	return visits[len(visits)-min(n, len(visits)):]
+}
+
+func TestVisitStoreOwnsVisits(t *testing.T) {
+	store := &rowStore{rows: make(map[int][]byte)}
+	s := New(WithVisitStore(store))
+	start := time.Now().Add(-time.Hour)
+
+	for i, path := range []string{"/", "/style.css", "/about", "/", "/contact"} {
+		s.Import([]VisitInput{{Date: start.Add(time.Duration(i) * time.Minute), IP: "10.0.0.1", Path: path}})
+	}
+
+	recordPage(s, "10.0.0.2", "/")
+
+	// only the last dynamic visit of each visitor stays in memory
+	if len(s.Visits) != 2 || len(store.rows) != 6 {
+		t.Fatalf("%d visits in memory and %d in the store, want 2 and 6", len(s.Visits), len(store.rows))
+	}
+
+	if got := s.VisitsCount(); got != 6 {
+		t.Errorf("VisitsCount() = %d, want 6", got)
+	}
+
+	if got := s.GetPage("/").VisitsCount(); got != 3 {
+		t.Errorf("/ has %d visits, want 3", got)
+	}
+
+	// the time spent set once the visit left memory reached the store
+	if visit := s.GetVisit(3); visit.ConcretePath != "/about" || visit.TimeSpent != time.Minute {
+		t.Errorf("GetVisit(3) = %s with %v spent, want /about with 1m", visit.ConcretePath, visit.TimeSpent)
+	}
+
+	// rebuilt visits are linked to the visitor and the page kept in memory
+	if visit := s.GetVisit(1); visit.VisitedBy.DynamicVisits != 4 || visit.Page.PriorVisits != 2 {
+		t.Errorf("GetVisit(1) isn't linked to its visitor and page")
+	}
+
+	if got := len(s.VisitsBetween(start, start.Add(3*time.Minute))); got != 3 {
+		t.Errorf("VisitsBetween returned %d visits, want 3", got)
+	}
+
+	if last := s.LastNVisits(2); len(last) != 2 || last[0].VisitedBy.IP != "10.0.0.2" {
+		t.Errorf("LastNVisits(2) = %v, want the visit of 10.0.0.2 first", last)
+	}
+
+	if r := s.RangeStats(start, time.Now().Add(time.Second)); r.VisitsCount() != 6 || r.VisitorsCount() != 2 {
+		t.Errorf("RangeStats has %d visits and %d visitors, want 6 and 2", r.VisitsCount(), r.VisitorsCount())
+	}
+
+	if got := s.OnlineNow(time.Hour + time.Minute); got != 2 {
+		t.Errorf("OnlineNow() = %d, want 2", got)
+	}
+
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := s.Snapshot().Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVisitStoreDropsFlushedVisits(t *testing.T) {
+	store := &rowStore{rows: make(map[int][]byte)}
+	s := New(WithVisitStore(store), WithMetricsFlusher(NoopFlusher{}, 0), WithDropFlushedVisits())
+
+	for i := 0; i < 3; i++ {
+		s.Record(context.Background(), VisitInput{IP: "10.0.0.1", Path: "/", CodeIssued: http.StatusOK, ContentType: "text/html"})
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(s.Visits) != 0 || len(store.rows) != 0 {
+		t.Errorf("%d visits in memory and %d in the store after the flush, want none", len(s.Visits), len(store.rows))
+	}
+}
+
+func BenchmarkStoreBetween(b *testing.B) {
+	_, store, start := benchmarkVisits()
+	from, to := start.Add(time.Hour), start.Add(2*time.Hour)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		store.Between(from, to)
+	}
+}
+
+// BenchmarkScanBetween is the range query without the store, a scan of the
+// Visits map followed by a sort
+func BenchmarkScanBetween(b *testing.B) {
+	visits, _, start := benchmarkVisits()
+	from, to := start.Add(time.Hour), start.Add(2*time.Hour)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		between := []*Visit{}
+
+		for _, v := range visits {
+			if !v.Date.Before(from) && v.Date.Before(to) {
+				between = append(between, v)
+			}
+		}
+
+		SortVisits(between)
+	}
+}
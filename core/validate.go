@@ -0,0 +1,137 @@
+package core
+
+import (
+	"fmt"
+)
+
+// Validate checks the consistency of the pointer graph: every visit is
+// referenced by its page, its visitor, its user and the store, the pages,
+// histories and users are sorted by date and the counters match. It reports
+// the first problem found
+func (s *Statistics) Validate() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, visit := range s.Visits {
+		if visit.ID != id {
+			return fmt.Errorf("visit %d: stored under ID %d", visit.ID, id)
+		}
+
+		if id > int(s.currentVisitID.Load()) {
+			return fmt.Errorf("visit %d: ID above the counter %d", id, s.currentVisitID.Load())
+		}
+
+		if visit.Page == nil || s.Pages[visit.Page.Path] != visit.Page {
+			return fmt.Errorf("visit %d: page missing from Pages", id)
+		}
+
+		if visit.VisitedBy == nil || s.Visitors[visitorKey(visit.VisitedBy)] != visit.VisitedBy {
+			return fmt.Errorf("visit %d: visitor missing from Visitors", id)
+		}
+
+		if _, ok := s.store.Get(id); !ok {
+			return fmt.Errorf("visit %d: missing from the store", id)
+		}
+	}
+
+	checkVisits := func(owner string, visits []*Visit, belongs func(*Visit) bool) error {
+		for i, visit := range visits {
+			if s.Visits[visit.ID] != visit {
+				return fmt.Errorf("%s: visit %d missing from Visits", owner, visit.ID)
+			}
+
+			if !belongs(visit) {
+				return fmt.Errorf("%s: visit %d points elsewhere", owner, visit.ID)
+			}
+
+			if i > 0 && visit.Date.Before(visits[i-1].Date) {
+				return fmt.Errorf("%s: visit %d out of date order", owner, visit.ID)
+			}
+		}
+
+		return nil
+	}
+
+	pageVisits := 0
+
+	for path, page := range s.Pages {
+		if page.Path != path {
+			return fmt.Errorf("page %s: stored under %s", page.Path, path)
+		}
+
+		err := checkVisits("page "+path, page.Visits, func(v *Visit) bool {
+			return v.Page == page
+		})
+		if err != nil {
+			return err
+		}
+
+		pageVisits += len(page.Visits)
+	}
+
+	historyVisits := 0
+
+	for key, visitor := range s.Visitors {
+		if visitorKey(visitor) != key {
+			return fmt.Errorf("visitor %s: stored under %s", visitorKey(visitor), key)
+		}
+
+		err := checkVisits("visitor "+key, visitor.History, func(v *Visit) bool {
+			return v.VisitedBy == visitor
+		})
+		if err != nil {
+			return err
+		}
+
+		historyVisits += len(visitor.History)
+	}
+
+	if pageVisits != len(s.Visits) || historyVisits != len(s.Visits) {
+		return fmt.Errorf("%d visits but %d in the pages and %d in the histories", len(s.Visits), pageVisits, historyVisits)
+	}
+
+	userVisits := 0
+
+	for userID, visits := range s.Users {
+		err := checkVisits("user "+userID, visits, func(v *Visit) bool {
+			return v.UserID == userID
+		})
+		if err != nil {
+			return err
+		}
+
+		userVisits += len(visits)
+	}
+
+	identified := 0
+
+	for _, visit := range s.Visits {
+		if visit.UserID != "" {
+			identified++
+		}
+	}
+
+	if userVisits != identified {
+		return fmt.Errorf("%d visits with a user ID but %d in Users", identified, userVisits)
+	}
+
+	// the store also holds the visits offloaded by WithVisitStore
+	if stored := len(s.store.All()); stored != len(s.Visits) && !s.offloadVisits {
+		return fmt.Errorf("%d visits but %d in the store", len(s.Visits), stored)
+	}
+
+	if s.VisitsCount() != len(s.Visits)+s.importedVisits+s.removedVisits || s.VisitorsCount() != len(s.Visitors)+s.importedVisitors {
+		return fmt.Errorf("visits or visitors count inconsistent with the recorded ones")
+	}
+
+	return nil
+}
+
+// visitorKey returns the key of the visitor in the Visitors map
+func visitorKey(visitor *Visitor) string {
+	if visitor.Fingerprint != "" {
+		return visitor.Fingerprint
+	}
+
+	return visitor.IP
+}
@@ -0,0 +1,25 @@
+package ginstats
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// SetUserID associates the current visit with an authenticated user, it must
+// be called by a handler running after the Middleware
+func SetUserID(c *gin.Context, userID string) {
+	c.Set("UserID", userID)
+}
+
+// SetFingerprint identifies the visitor of the current request by the given
+// fingerprint (e.g. a session cookie) instead of its IP. The Visitors map is
+// keyed by the fingerprint when one is set and by the IP otherwise, so the
+// methods taking an ip parameter accept a fingerprint too
+func SetFingerprint(c *gin.Context, fingerprint string) {
+	c.Set("Fingerprint", fingerprint)
+}
+
+// MarkConversion flags the current visit as a conversion (e.g. in a purchase
+// handler), see ConversionCount and ConvertedVisitorsRate
+func MarkConversion(c *gin.Context) {
+	c.Set("Converted", true)
+}
@@ -0,0 +1,106 @@
+package ginstats
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/qwaykee/statistics/core"
+
+	"bufio"
+	"net"
+	"time"
+)
+
+// hijackWriter records whether the connection has been hijacked, in which
+// case the status reported by gin is meaningless
+type hijackWriter struct {
+	gin.ResponseWriter
+	hijacked bool
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+
+	return conn, rw, err
+}
+
+// Middleware records each request in s once the handlers have returned, the
+// "PageType" context key overrides the classification of the visit. The
+// "VisitID" one is set to the ID of the visit before the handlers run, or
+// once it is recorded if the options may skip it, see ReserveVisitID
+func Middleware(s *core.Statistics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		visitID := s.ReserveVisitID()
+		if visitID != 0 {
+			c.Set("VisitID", visitID)
+		}
+
+		writer := &hijackWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		start := time.Now()
+
+		c.Next()
+
+		loadingTime := time.Since(start)
+
+		c.Writer = writer.ResponseWriter
+
+		// a handler that never writes gets the status gin sends once the chain
+		// returns (200 by default), hijacked connections get StatusHijacked
+		codeIssued := c.Writer.Status()
+		if writer.hijacked {
+			codeIssued = core.StatusHijacked
+		}
+
+		input := core.VisitInput{
+			ID: visitID,
+			IP: c.ClientIP(),
+			Fingerprint: c.GetString("Fingerprint"),
+			Path: c.FullPath(),
+			ConcretePath: c.Request.URL.Path,
+			CodeIssued: codeIssued,
+			LoadingTime: loadingTime,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			ContentLanguage: c.Writer.Header().Get("Content-Language"),
+			ContentEncoding: c.Writer.Header().Get("Content-Encoding"),
+			Query: c.Request.URL.RawQuery,
+			Referer: c.GetHeader("Referer"),
+			UserAgent: c.Request.UserAgent(),
+			AcceptLanguage: c.GetHeader("Accept-Language"),
+			UserID: c.GetString("UserID"),
+			Converted: c.GetBool("Converted"),
+			Protocol: c.Request.Proto,
+			TLS: c.Request.TLS != nil,
+		}
+
+		if pageType, ok := c.Get("PageType"); ok {
+			input.Type, _ = pageType.(core.PageType)
+		}
+
+		if c.Request.ContentLength > 0 {
+			input.RequestSize = c.Request.ContentLength
+		}
+
+		if size := c.Writer.Size(); size > 0 {
+			input.ResponseSize = int64(size)
+		}
+
+		if visit, ok := s.Record(c.Request.Context(), input); ok && visitID == 0 {
+			c.Set("VisitID", visit.ID)
+		}
+	}
+}
+
+// RegisterRoutes registers the routes of engine.Routes() in s, the pages being
+// keyed by route
+func RegisterRoutes(s *core.Statistics, routes gin.RoutesInfo) {
+	paths := []string{}
+
+	for _, route := range routes {
+		paths = append(paths, route.Path)
+	}
+
+	s.RegisterRoutes(paths...)
+}
@@ -0,0 +1,156 @@
+package ginstats
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/qwaykee/statistics/core"
+
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newEngine returns an engine recording in a new Statistics, each recorded
+// visit is sent to the returned channel
+func newEngine(options ...core.Option) (*gin.Engine, *core.Statistics, chan *core.Visit) {
+	visits := make(chan *core.Visit, 16)
+	s := core.New(append(options, core.WithOnVisit(func(v *core.Visit) {
+		visits <- v
+	}))...)
+
+	engine := gin.New()
+	engine.Use(Middleware(s))
+
+	return engine, s, visits
+}
+
+func serve(engine *gin.Engine, method, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+
+	return recorder
+}
+
+func receive(t *testing.T, visits chan *core.Visit) *core.Visit {
+	t.Helper()
+
+	select {
+	case v := <-visits:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("no visit recorded")
+		return nil
+	}
+}
+
+func TestMiddlewareHijackedConnection(t *testing.T) {
+	engine, _, visits := newEngine()
+
+	engine.GET("/ws", func(c *gin.Context) {
+		conn, _, err := c.Writer.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		conn.Close()
+	})
+
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	if response, err := http.Get(server.URL + "/ws"); err == nil {
+		response.Body.Close()
+		t.Errorf("got a %d response from a hijacked connection", response.StatusCode)
+	}
+
+	if v := receive(t, visits); v.CodeIssued != core.StatusHijacked {
+		t.Errorf("CodeIssued = %d, want StatusHijacked", v.CodeIssued)
+	}
+}
+
+func TestMiddlewareHandlerNeverWrites(t *testing.T) {
+	engine, _, visits := newEngine()
+
+	engine.GET("/empty", func(c *gin.Context) {})
+
+	recorder := serve(engine, http.MethodGet, "/empty")
+
+	if v := receive(t, visits); v.CodeIssued != recorder.Code {
+		t.Errorf("CodeIssued = %d, want the %d sent by gin", v.CodeIssued, recorder.Code)
+	}
+}
+
+func TestMiddlewareSetsVisitIDBeforeHandlers(t *testing.T) {
+	engine, _, visits := newEngine()
+
+	var handlerID any
+	engine.GET("/", func(c *gin.Context) {
+		handlerID, _ = c.Get("VisitID")
+		c.String(http.StatusOK, "ok")
+	})
+
+	serve(engine, http.MethodGet, "/")
+
+	if v := receive(t, visits); handlerID != v.ID {
+		t.Errorf("the handler read the VisitID %v, want the %d of the recorded visit", handlerID, v.ID)
+	}
+}
+
+func TestMiddlewareIgnoredStatusCode(t *testing.T) {
+	engine, s, visits := newEngine(core.WithIgnoredStatusCodes(http.StatusNotModified))
+
+	engine.GET("/cached", func(c *gin.Context) {
+		c.Status(http.StatusNotModified)
+	})
+	engine.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	serve(engine, http.MethodGet, "/cached")
+
+	if s.VisitsCount() != 0 || s.HasPage("/cached") {
+		t.Fatalf("the 304 response was recorded")
+	}
+
+	serve(engine, http.MethodGet, "/")
+
+	// the ignored request didn't consume an ID
+	if v := receive(t, visits); v.ID != 1 {
+		t.Errorf("the first recorded visit has the ID %d, want 1", v.ID)
+	}
+}
+
+func TestMiddlewareParameterizedRoute(t *testing.T) {
+	engine, s, visits := newEngine()
+
+	engine.GET("/user/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	serve(engine, http.MethodGet, "/user/42")
+	v := receive(t, visits)
+
+	if v.Page.Path != "/user/:id" || v.ConcretePath != "/user/42" {
+		t.Errorf("the visit is keyed by %q with the concrete path %q, want /user/:id and /user/42", v.Page.Path, v.ConcretePath)
+	}
+
+	if v.ResponseSize != int64(len("42")) {
+		t.Errorf("ResponseSize = %d, want the %d bytes written", v.ResponseSize, len("42"))
+	}
+
+	if !s.HasPage("/user/:id") || s.HasPage("/user/42") {
+		t.Errorf("the page isn't keyed by the route")
+	}
+
+	// a request matching no route is keyed by its path
+	serve(engine, http.MethodGet, "/unknown")
+
+	if v := receive(t, visits); v.Page.Path != "/unknown" || v.ConcretePath != "/unknown" {
+		t.Errorf("an unmatched request is keyed by %q with the concrete path %q, want /unknown", v.Page.Path, v.ConcretePath)
+	}
+}
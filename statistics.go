@@ -0,0 +1,133 @@
+package statistics
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/qwaykee/statistics/core"
+	"github.com/qwaykee/statistics/ginstats"
+)
+
+// Statistics is a core.Statistics with the gin Middleware, the analytics
+// engine lives in statistics/core which doesn't depend on gin and the gin
+// adapter in statistics/ginstats
+type Statistics struct {
+	*core.Statistics
+}
+
+func New(options ...Option) *Statistics {
+	return &Statistics{core.New(options...)}
+}
+
+func (s *Statistics) Middleware() gin.HandlerFunc {
+	return ginstats.Middleware(s.Statistics)
+}
+
+// RegisterGinRoutes registers the routes of engine.Routes(), the pages being
+// keyed by route
+func (s *Statistics) RegisterGinRoutes(routes gin.RoutesInfo) {
+	ginstats.RegisterRoutes(s.Statistics, routes)
+}
+
+// the rest re-exports statistics/core and statistics/ginstats so the code
+// written before the split keeps building
+
+type (
+	AccessLogError = core.AccessLogError
+	Aggregates = core.Aggregates
+	Cohort = core.Cohort
+	IntervalMetrics = core.IntervalMetrics
+	JourneyStep = core.JourneyStep
+	LanguageCount = core.LanguageCount
+	MemoryVisitStore = core.MemoryVisitStore
+	MetricsFlusher = core.MetricsFlusher
+	NoopFlusher = core.NoopFlusher
+	Option = core.Option
+	Page = core.Page
+	PageComparison = core.PageComparison
+	PageCount = core.PageCount
+	PageScore = core.PageScore
+	PageShare = core.PageShare
+	PageStats = core.PageStats
+	PageSummary = core.PageSummary
+	PageTrend = core.PageTrend
+	PageType = core.PageType
+	Percentile = core.Percentile
+	RangeStats = core.RangeStats
+	Report = core.Report
+	StatsDiff = core.StatsDiff
+	Summary = core.Summary
+	TrendPoint = core.TrendPoint
+	Visit = core.Visit
+	VisitDTO = core.VisitDTO
+	VisitInput = core.VisitInput
+	VisitStore = core.VisitStore
+	Visitor = core.Visitor
+	VisitorSummary = core.VisitorSummary
+)
+
+const (
+	Dynamic = core.Dynamic
+	Static = core.Static
+
+	UnmatchedPath = core.UnmatchedPath
+	OtherPath = core.OtherPath
+
+	StatusHijacked = core.StatusHijacked
+
+	MetricVisits = core.MetricVisits
+	MetricVisitors = core.MetricVisitors
+
+	ClientBrowser = core.ClientBrowser
+	ClientApp = core.ClientApp
+	ClientBot = core.ClientBot
+	ClientOther = core.ClientOther
+
+	UnknownLanguage = core.UnknownLanguage
+	DirectTraffic = core.DirectTraffic
+
+	AccessLogCommon = core.AccessLogCommon
+	AccessLogCombined = core.AccessLogCombined
+)
+
+var (
+	WithAppUserAgents = core.WithAppUserAgents
+	WithAutosave = core.WithAutosave
+	WithCurrentVisitorWindow = core.WithCurrentVisitorWindow
+	WithDropFlushedVisits = core.WithDropFlushedVisits
+	WithDynamicOnly = core.WithDynamicOnly
+	WithIgnoredStatusCodes = core.WithIgnoredStatusCodes
+	WithMaxHistoryPerVisitor = core.WithMaxHistoryPerVisitor
+	WithMaxPages = core.WithMaxPages
+	WithMetricsFlusher = core.WithMetricsFlusher
+	WithOnVisit = core.WithOnVisit
+	WithPrimaryLanguageOnly = core.WithPrimaryLanguageOnly
+	WithQueryStrings = core.WithQueryStrings
+	WithRefererBlocklist = core.WithRefererBlocklist
+	WithRefererHostOnly = core.WithRefererHostOnly
+	WithSlogLogger = core.WithSlogLogger
+	WithStaticExtensions = core.WithStaticExtensions
+	WithSuccessfulOnly = core.WithSuccessfulOnly
+	WithSummaryPercentiles = core.WithSummaryPercentiles
+	WithTrackOnlyMatchedRoutes = core.WithTrackOnlyMatchedRoutes
+	WithVisitStore = core.WithVisitStore
+	WithVisitorIDSalt = core.WithVisitorIDSalt
+	WithWarmupPeriod = core.WithWarmupPeriod
+	WithoutStaticExtensions = core.WithoutStaticExtensions
+	WithoutTimeSpent = core.WithoutTimeSpent
+
+	And = core.And
+	Or = core.Or
+	Not = core.Not
+	ByPath = core.ByPath
+	ByStatusClass = core.ByStatusClass
+	ByTimeRange = core.ByTimeRange
+	ByType = core.ByType
+
+	Diff = core.Diff
+	SortVisits = core.SortVisits
+	ParseAccessLog = core.ParseAccessLog
+	NewMemoryVisitStore = core.NewMemoryVisitStore
+
+	SetUserID = ginstats.SetUserID
+	SetFingerprint = ginstats.SetFingerprint
+	MarkConversion = ginstats.MarkConversion
+)
@@ -32,10 +32,7 @@ func main() {
 
 	r.GET("/stats", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
-			"VisitsCount": st.VisitsCount(),
-			"VisitorsCount": st.VisitorsCount(),
-			"EstimatedCurrentVisitors": st.EstimatedCurrentVisitors(),
-			"LanguagesCount": st.LanguagesCount(),
+			"Summary": st.Summary(),
 			"Time": time.Now().Format(time.RFC3339),
 		})
 	})
@@ -47,14 +44,14 @@ func main() {
 			return
 		}
 
-		visit, err := st.GetVisitor(c.ClientIP()).GetVisit(date)
-		if err != nil {
-			log.Println(err)
+		visit := st.GetVisitor(c.ClientIP()).GetVisitNearest(date)
+		if visit == nil {
+			log.Println("visit not found")
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"Visit": visit,
+			"Visit": visit.ToDTO(),
 		})
 	})
 